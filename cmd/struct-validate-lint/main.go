@@ -0,0 +1,13 @@
+// Command struct-validate-lint checks struct validation tags and the generated
+// _validate.go files. It can run standalone or through go vet -vettool.
+package main
+
+import (
+	"SJT/struct-validate/pkg/analyzer"
+
+	"golang.org/x/tools/go/analysis/singlechecker"
+)
+
+func main() {
+	singlechecker.Main(analyzer.Analyzer)
+}
@@ -2,14 +2,181 @@ package main
 
 import (
 	"SJT/struct-validate/pkg"
+	"SJT/struct-validate/test_data/b"
 	"SJT/struct-validate/test_data/b/c/d"
-	"fmt"
+	"SJT/struct-validate/test_data/lint"
+	"SJT/struct-validate/utils"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
 )
 
 func TestGen(t *testing.T) {
 	g := pkg.NewGenDefinition()
-	fmt.Println(g.Gen(d.Nested{}))
+	assert.NoError(t, g.Gen(d.Nested{}, b.Category{}, b.Chapter{}, b.Section{}, b.Route{}, b.Trip{}, b.Envelope{}, b.Article{}, b.Catalog{},
+		pkg.Decl{Dir: "test_data/b", Name: "Page"}, pkg.Decl{Dir: "test_data/b", Name: "List"}))
+}
+
+func TestGenerate(t *testing.T) {
+	files, err := pkg.NewGenDefinition().Generate(d.Nested{})
+	assert.NoError(t, err)
+	wd, err := os.Getwd()
+	assert.NoError(t, err)
+
+	paths := make([]string, 0, len(files))
+	for path, code := range files {
+		rel, err := filepath.Rel(wd, path)
+		assert.NoError(t, err)
+		paths = append(paths, filepath.ToSlash(rel))
+		onDisk, err := os.ReadFile(path)
+		assert.NoError(t, err)
+		assert.Equal(t, string(onDisk), string(code), rel)
+	}
+	sort.Strings(paths)
+	assert.Equal(t, []string{
+		"test_data/b/address_validate.go",
+		"test_data/b/c/d/nested_validate.go",
+		"test_data/b/detail_validate.go",
+	}, paths)
+}
+
+func TestGenerateCombined(t *testing.T) {
+	g := pkg.NewGenDefinition()
+	g.SetCombine(true)
+	files, err := g.Generate(d.Nested{})
+	assert.NoError(t, err)
+	wd, err := os.Getwd()
+	assert.NoError(t, err)
+
+	paths := make([]string, 0, len(files))
+	for path := range files {
+		rel, err := filepath.Rel(wd, path)
+		assert.NoError(t, err)
+		paths = append(paths, filepath.ToSlash(rel))
+	}
+	sort.Strings(paths)
+	assert.Equal(t, []string{
+		"test_data/b/c/d/" + pkg.CombinedFileName,
+		"test_data/b/" + pkg.CombinedFileName,
+	}, paths)
+
+	code := string(files[filepath.Join(wd, "test_data/b/c/d", pkg.CombinedFileName)])
+	assert.Contains(t, code, "// types:\n// \tNested\n")
+	assert.Contains(t, code, "\tregexpEmail = regexp.MustCompile(")
+	assert.Contains(t, code, "if !regexpEmail.MatchString(t.Email) {")
+	assert.Equal(t, 1, strings.Count(code, "regexp.MustCompile("+"`^(([^<>()"))
+
+	// 嵌套结构体所在的包重写整个文件，保留本次没有到达的结构体
+	code = string(files[filepath.Join(wd, "test_data/b", pkg.CombinedFileName)])
+	assert.Contains(t, code, "// types:\n// \tAddress\n// \tArticle\n")
+	for _, name := range []string{"Category", "Detail", "Envelope", "Page", "Stop", "Trip"} {
+		assert.Contains(t, code, "// \t"+name+"\n")
+	}
+	assert.Contains(t, code, "func (t *Page[T]) Validator() error {")
+}
+
+func TestGenerateLocal(t *testing.T) {
+	g := pkg.NewGenDefinition()
+	g.SetLocal(true)
+	files, err := g.Generate(d.Nested{})
+	assert.NoError(t, err)
+	wd, err := os.Getwd()
+	assert.NoError(t, err)
+
+	file := filepath.Join(wd, "test_data/b/c/d/nested_validate.go")
+	assert.Len(t, files, 1)
+	code := string(files[file])
+	assert.Contains(t, code, "if err := validateNestedAddress(ctx, &t.Address); err != nil {")
+	assert.Contains(t, code, "if err := validateNestedAddress(ctx, t.Addr); err != nil {")
+	assert.Contains(t, code, "func validateNestedDetail(ctx context.Context, v *b.Detail) error {")
+	assert.Equal(t, 1, strings.Count(code, "func validateNestedAddress("))
+}
+
+func TestGenerateMain(t *testing.T) {
+	files, err := pkg.NewGenDefinition().Generate(pkg.Decl{Dir: "test_data/cmd/app", Name: "CreateUser"})
+	assert.NoError(t, err)
+	wd, err := os.Getwd()
+	assert.NoError(t, err)
+	paths := make([]string, 0, len(files))
+	for path, code := range files {
+		rel, err := filepath.Rel(wd, path)
+		assert.NoError(t, err)
+		paths = append(paths, filepath.ToSlash(rel))
+		assert.Contains(t, string(code), "\npackage main\n")
+	}
+	sort.Strings(paths)
+	assert.Equal(t, []string{
+		"test_data/cmd/app/create_user_validate.go",
+		"test_data/cmd/app/profile_validate.go",
+	}, paths)
+
+	// 程序调用生成的验证方法
+	out, err := exec.Command("go", "run", "./test_data/cmd/app").CombinedOutput()
+	assert.Error(t, err)
+	assert.Contains(t, string(out), "age必须 lte 150")
+}
+
+func TestGenCheck(t *testing.T) {
+	tests := []struct {
+		name     string
+		entities []any
+		wantErr  string
+	}{
+		{name: "up to date", entities: []any{d.Nested{}, b.Category{}, lint.Fresh{}}},
+		{name: "stale", entities: []any{lint.Stale{}}, wantErr: "stale_validate.go"},
+		{name: "missing", entities: []any{lint.Missing{}}, wantErr: "missing_validate.go"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := pkg.NewGenDefinition()
+			g.SetCheck(true)
+			err := g.Gen(tt.entities...)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+	assert.NoFileExists(t, "test_data/lint/missing_validate.go")
+}
+
+func TestGenPrune(t *testing.T) {
+	dir, err := filepath.Abs("test_data/b")
+	assert.NoError(t, err)
+	old := filepath.Join(dir, "renamed_validate.go")
+	manual := filepath.Join(dir, "manual_validate.go")
+	assert.NoError(t, os.WriteFile(old, []byte(pkg.GeneratedHeader+"\n\npackage b\n"), 0644))
+	assert.NoError(t, os.WriteFile(manual, []byte("package b\n"), 0644))
+	defer os.Remove(old)
+	defer os.Remove(manual)
+
+	g := pkg.NewGenDefinition()
+	g.SetCheck(true)
+	g.SetPrune(dir)
+	assert.ErrorContains(t, g.Gen(b.Category{}, b.Chapter{}, b.Section{}, b.Route{}, b.Trip{}, b.Address{}, b.Envelope{}, b.Article{}, b.Catalog{}, pkg.Decl{Dir: dir, Name: "Page"}, pkg.Decl{Dir: dir, Name: "List"}), "renamed_validate.go")
+	assert.FileExists(t, old)
+
+	g = pkg.NewGenDefinition()
+	g.SetPrune(dir)
+	assert.NoError(t, g.Gen(b.Category{}, b.Chapter{}, b.Section{}, b.Route{}, b.Trip{}, b.Address{}, b.Envelope{}, b.Article{}, b.Catalog{}, pkg.Decl{Dir: dir, Name: "Page"}, pkg.Decl{Dir: dir, Name: "List"}))
+	assert.NoFileExists(t, old)
+	assert.FileExists(t, manual)
+	assert.FileExists(t, filepath.Join(dir, "detail_validate.go"))
+}
+
+func TestResolverDirs(t *testing.T) {
+	dirs, err := utils.ListPackages([]string{"./test_data/..."}, []string{"test_data/diag", "test_data/lint"})
+	assert.NoError(t, err)
+	s := pkg.ScanFile{Dirs: dirs, Check: true}
+	assert.NoError(t, s.Resolver())
 }
 
 func TestValidate(t *testing.T) {
@@ -34,3 +201,213 @@ func TestValidate(t *testing.T) {
 	//	fmt.Println(err)
 	//}
 }
+
+func TestValidateFields(t *testing.T) {
+	n := &d.Nested{Addr: &b.Address{AddressId: 1}}
+	assert.EqualError(t, n.ValidateFields("name"), "name不能为空")
+	assert.NoError(t, n.ValidateFields("addr.city", "map"))
+	assert.EqualError(t, n.ValidateFields("addr.address_id"), "address_id必须 gt 10")
+	assert.EqualError(t, n.ValidateFields("addr.detail"), "detail不能为空")
+	assert.EqualError(t, n.ValidateFields("Addr.AddressId"), "address_id必须 gt 10")
+	assert.EqualError(t, n.ValidateFields("map.x"), "未知字段: map.x")
+	assert.EqualError(t, n.ValidateFields("unknown"), "未知字段: unknown")
+}
+
+func TestValidateContext(t *testing.T) {
+	a := &b.Address{AddressId: 11, City: "杭州", Detail: b.Detail{Detail: "1"}}
+	ctx := context.WithValue(context.Background(), b.ClosedCity{}, "杭州")
+	assert.NoError(t, a.Validator())
+	assert.EqualError(t, a.ValidateContext(ctx), "city 暂停服务")
+
+	// 嵌套结构体使用同一个 ctx
+	id := 1
+	n := &d.Nested{
+		Id:      &id,
+		Name:    "1",
+		Score:   1,
+		Email:   "a@b.cn",
+		Min:     "12345",
+		MyUUID:  "5f0e3bb2-9a2b-4c1c-8f1e-0a1b2c3d4e5f",
+		Slice:   []int{1},
+		Chan:    make(chan int),
+		Address: b.Address{AddressId: 11, Detail: b.Detail{Detail: "1"}},
+		Addr:    a,
+		Phone:   "13800000000",
+	}
+	assert.NoError(t, n.Validator())
+	assert.EqualError(t, n.ValidateContext(ctx), "city 暂停服务")
+}
+
+func TestValidateInterface(t *testing.T) {
+	e := &b.Envelope{Kind: "address"}
+	assert.EqualError(t, e.Validator(), "Payload 不能为nil ")
+	e.Payload = "1"
+	assert.NoError(t, e.Validator())
+	assert.EqualError(t, e.ValidateFields("payload.city"), "未知字段: payload.city")
+
+	a := &b.Address{AddressId: 1, City: "杭州", Detail: b.Detail{Detail: "1"}}
+	e.Payload = a
+	assert.EqualError(t, e.Validator(), "address_id必须 gt 10")
+	assert.EqualError(t, e.ValidateFields("payload.address_id"), "address_id必须 gt 10")
+	assert.NoError(t, e.ValidateFields("payload.city"))
+
+	// 动态值使用同一个 ctx
+	a.AddressId = 11
+	ctx := context.WithValue(context.Background(), b.ClosedCity{}, "杭州")
+	assert.NoError(t, e.Validator())
+	assert.EqualError(t, e.ValidateContext(ctx), "city 暂停服务")
+
+	// 指针接收者的方法不属于值的方法集
+	e.Payload = *a
+	assert.NoError(t, e.ValidateContext(ctx))
+
+	// nil 指针跳过
+	e.Payload = (*b.Address)(nil)
+	assert.NoError(t, e.Validator())
+	assert.NoError(t, e.ValidateFields("payload", "payload.city"))
+}
+
+func TestValidateEmbedded(t *testing.T) {
+	a := &b.Article{Title: "t"}
+	assert.EqualError(t, a.Validator(), "sort必须 gt 0")
+	assert.EqualError(t, a.ValidateFields("sort"), "sort必须 gt 0")
+	assert.EqualError(t, a.ValidateFields("audited.base.sort"), "sort必须 gt 0")
+	a.Sort = 1
+	assert.EqualError(t, a.Validator(), "editor不能为空")
+	a.Editor = "e"
+	// Meta 为 nil，Address 被跳过
+	assert.NoError(t, a.Validator())
+	assert.NoError(t, a.ValidateFields("lang"))
+	assert.EqualError(t, a.ValidateFields("address_id"), "未知字段: address_id")
+
+	a.Meta = &b.Meta{}
+	assert.EqualError(t, a.Validator(), "lang不能为空")
+	assert.EqualError(t, a.ValidateFields("Lang"), "lang不能为空")
+	assert.NoError(t, a.ValidateFields("title", "editor"))
+}
+
+func TestValidateGeneric(t *testing.T) {
+	p := &b.Page[*b.Address]{Items: []*b.Address{{AddressId: 11, Detail: b.Detail{Detail: "1"}}, {AddressId: 1}}}
+	assert.EqualError(t, p.Validator(), "address_id必须 gt 10")
+	assert.EqualError(t, p.ValidateFields("items"), "address_id必须 gt 10")
+	p.Items[1] = p.Items[0]
+	assert.NoError(t, p.Validator())
+	// dive 跳过 nil 元素
+	p.Items[1] = nil
+	assert.NoError(t, p.Validator())
+	assert.NoError(t, p.ValidateFields("items"))
+	assert.NoError(t, (&b.Page[*b.Address]{Items: []*b.Address{nil}}).Validator())
+	p.Items = append(p.Items, nil)
+	assert.EqualError(t, p.Validator(), "items必须 max 3")
+	assert.NoError(t, (&b.Page[int]{Items: []int{1}}).Validator())
+
+	a := &b.Address{AddressId: 11, City: "杭州", Detail: b.Detail{Detail: "1"}}
+	l := &b.List[*b.Address]{Head: a}
+	assert.EqualError(t, l.Validator(), "Items 不能为nil ")
+	l.Items = []*b.Address{{AddressId: 1}}
+	assert.EqualError(t, l.Validator(), "address_id必须 gt 10")
+	l.Items[0] = a
+	ctx := context.WithValue(context.Background(), b.ClosedCity{}, "杭州")
+	assert.NoError(t, l.Validator())
+	assert.EqualError(t, l.ValidateContext(ctx), "city 暂停服务")
+	assert.NoError(t, (&b.List[*b.Address]{Items: []*b.Address{nil}}).Validator())
+	assert.NoError(t, (&b.List[*b.Address]{Items: []*b.Address{a}}).ValidateFields("head", "items"))
+
+	c := &b.Catalog{}
+	assert.NoError(t, c.Validator())
+	c.Addresses.Items = []*b.Address{{AddressId: 1}}
+	assert.EqualError(t, c.Validator(), "address_id必须 gt 10")
+
+	_, err := pkg.NewGenDefinition().Generate(b.Page[int]{})
+	assert.ErrorContains(t, err, "generic struct Page[int]")
+	_, err = pkg.NewGenDefinition().Generate(pkg.Decl{Dir: "test_data/b", Name: "Missing"})
+	assert.ErrorContains(t, err, "未找到结构体 Missing")
+}
+
+func TestValidateFunc(t *testing.T) {
+	sku := "abc"
+	f := &lint.Fresh{Code: "a", Label: "A", Sku: &sku}
+	assert.EqualError(t, f.Validator(), "sku 必须是8位")
+	sku = "abcdefgh"
+	assert.NoError(t, f.Validator())
+	f.Label = "a"
+	assert.EqualError(t, f.Validator(), "label 未通过 isUpper 校验")
+	f.Label = "A"
+
+	// 指针为 nil 时不调用函数
+	f = &lint.Fresh{Code: "a", Label: "A"}
+	assert.NotPanics(t, func() { assert.NoError(t, f.Validator()) })
+	f.Backup, f.Alias = &sku, new(string)
+	assert.NoError(t, f.Validator())
+	*f.Alias = "a"
+	assert.EqualError(t, f.Validator(), "alias 未通过 isUpper 校验")
+	sku = "abc"
+	assert.EqualError(t, f.Validator(), "sku 必须是8位")
+
+	// 参数类型使用点导入和导入别名
+	delay := time.Hour
+	w := &lint.Timeout{Wait: time.Second, Delay: &delay}
+	assert.EqualError(t, w.Validator(), "delay 未通过 short 校验")
+	delay = time.Minute
+	assert.NoError(t, w.Validator())
+	w.Wait, w.Delay = 0, nil
+	assert.EqualError(t, w.Validator(), "wait 未通过 positive 校验")
+
+	_, err := pkg.NewGenDefinition().Generate(lint.Mismatched{})
+	assert.ErrorContains(t, err, "test_data/lint/lint.go:35:25: Mismatched.Count: func validSKU: cannot use int as string in argument")
+}
+
+func TestGenCustomFuncError(t *testing.T) {
+	_, err := pkg.NewGenDefinition().Generate(lint.Custom{})
+	assert.ErrorContains(t, err, "test_data/lint/lint.go:44:23: Custom.check: 自定义函数签名不正确")
+}
+
+func TestValidateOmitEmpty(t *testing.T) {
+	n := &d.Nested{}
+	assert.NoError(t, n.ValidateFields("contact"))
+	n.Contact = "123"
+	assert.EqualError(t, n.ValidateFields("contact"), "contact 的规则不匹配")
+}
+
+func TestValidateRecursive(t *testing.T) {
+	c := &b.Category{Name: "root"}
+	assert.NoError(t, c.Validator())
+	c.Parent = &b.Category{}
+	assert.EqualError(t, c.Validator(), "name不能为空")
+	assert.EqualError(t, c.ValidateFields("parent.name"), "name不能为空")
+
+	o := &b.Owner{Name: "owner"}
+	assert.EqualError(t, o.Validator(), "Category 不能为nil ")
+	o.Category = c
+	assert.EqualError(t, o.Validator(), "name不能为空")
+	c.Parent.Name = "parent"
+	assert.NoError(t, o.Validator())
+
+	// 从 Section 解析时 Chapter 的 Section 字段是值类型的递归字段，直接调用验证方法
+	files, err := pkg.NewGenDefinition().Generate(b.Section{})
+	assert.NoError(t, err)
+	wd, err := os.Getwd()
+	assert.NoError(t, err)
+	code := string(files[filepath.Join(wd, "test_data/b/chapter_validate.go")])
+	assert.Contains(t, code, "if err := t.Section.ValidateContext(ctx); err != nil {")
+	assert.Contains(t, code, "if err := t.Section.Validator(); err != nil {")
+	assert.NotContains(t, code, "t.Section != nil")
+}
+
+func TestGenerateDeterministic(t *testing.T) {
+	wd, err := os.Getwd()
+	assert.NoError(t, err)
+	file := filepath.Join(wd, "test_data/b/stop_validate.go")
+	// 嵌套结构体单独解析，生成的代码与从哪个结构体到达无关
+	first, err := pkg.NewGenDefinition().Generate(b.Route{}, b.Trip{})
+	assert.NoError(t, err)
+	second, err := pkg.NewGenDefinition().Generate(b.Trip{}, b.Route{})
+	assert.NoError(t, err)
+	assert.Equal(t, string(first[file]), string(second[file]))
+	assert.Contains(t, string(first[file]), "if t.Route != nil {")
+
+	trip := &b.Trip{Stop: b.Stop{Route: &b.Route{}}}
+	assert.EqualError(t, trip.Validator(), "name不能为空")
+	trip.Stop.Route.Name = "route"
+	assert.NoError(t, trip.Validator())
+}
@@ -0,0 +1,70 @@
+package internal
+
+// Promoted holds the fields promoted to an entity through one of its embedded
+// structs. ValidateFields validates them by passing the path to the embedded
+// struct's ValidateFields.
+type Promoted struct {
+	Embedded *Node    // Embedded 声明在实体中的嵌入字段
+	Labels   []string // Labels 提升字段在路径中的名称，下划线形式和Go字段名
+}
+
+// PromotedFields returns the fields promoted to e from its embedded structs that
+// have a validator, following the Go rules: a field at a shallower depth shadows
+// the deeper ones, and the fields of the same name at the same depth are not promoted.
+func (e *Entity) PromotedFields() []*Promoted {
+	type embedded struct {
+		top  *Node // top 实体中的嵌入字段
+		node *Node
+	}
+	seen := make(map[string]struct{}, len(e.Fields))
+	labels := make(map[string]struct{}, len(e.Fields)*2)
+	var level []embedded
+	for _, f := range e.Fields {
+		seen[f.Field] = struct{}{}
+		labels[f.Field] = struct{}{}
+		labels[f.FieldPath()] = struct{}{}
+		if f.Embedded && f.HasValidator() {
+			level = append(level, embedded{top: f, node: f})
+		}
+	}
+
+	var promoted []*Promoted
+	byEmbedded := make(map[*Node]*Promoted, len(level))
+	for len(level) > 0 {
+		count := make(map[string]int, 10)
+		for _, em := range level {
+			for _, f := range em.node.Fields {
+				count[f.Field]++
+			}
+		}
+		var next []embedded
+		for _, em := range level {
+			for _, f := range em.node.Fields {
+				if _, ok := seen[f.Field]; ok {
+					continue
+				}
+				// 下划线形式与其他字段相同时不能作为 case
+				if _, ok := labels[f.FieldPath()]; !ok && count[f.Field] == 1 {
+					labels[f.Field] = struct{}{}
+					labels[f.FieldPath()] = struct{}{}
+					p, ok := byEmbedded[em.top]
+					if !ok {
+						p = &Promoted{Embedded: em.top}
+						byEmbedded[em.top] = p
+						promoted = append(promoted, p)
+					}
+					p.Labels = append(p.Labels, f.FieldPath(), f.Field)
+				}
+				if f.Embedded && f.HasValidator() && count[f.Field] == 1 {
+					next = append(next, embedded{top: em.top, node: f})
+				}
+			}
+		}
+		// 同一深度的字段，无论是否有歧义，都会遮蔽更深的字段
+		for name := range count {
+			seen[name] = struct{}{}
+		}
+		level = next
+	}
+	return promoted
+}
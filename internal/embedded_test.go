@@ -0,0 +1,53 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type Inner struct {
+	Code string `check:"notEmpty"`
+	Name string
+}
+
+type Middle struct {
+	Inner
+	Level int `check:"gt 0"`
+}
+
+type Other struct {
+	Name  string
+	Level int
+}
+
+type Outer struct {
+	Middle
+	*Other
+	Address `check:"-"`
+	Title   string `check:"notEmpty"`
+}
+
+func TestParserEmbedded(t *testing.T) {
+	e := NewEntity()
+	require.NoError(t, e.Parser(Outer{}))
+	require.Len(t, e.Fields, 3)
+
+	middle, other := e.Fields[0], e.Fields[1]
+	assert.True(t, middle.Embedded)
+	assert.True(t, middle.ValidatesNested())
+	assert.True(t, middle.Fields[0].Embedded)
+	assert.True(t, other.Embedded)
+	assert.False(t, other.ValidatesNested())
+	assert.True(t, other.ValidatesNestedNotNil())
+	assert.False(t, e.Fields[2].Embedded)
+
+	// Level 在同一深度有歧义，Inner.Name 被 Other.Name 遮蔽
+	promoted := e.PromotedFields()
+	require.Len(t, promoted, 2)
+	assert.Equal(t, middle, promoted[0].Embedded)
+	assert.Equal(t, []string{"inner", "Inner", "code", "Code"}, promoted[0].Labels)
+	assert.Equal(t, other, promoted[1].Embedded)
+	assert.Equal(t, []string{"name", "Name"}, promoted[1].Labels)
+}
@@ -0,0 +1,190 @@
+package internal
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// TagFunc is a package level function that the func rule calls on a field.
+type TagFunc struct {
+	Name   string         // Name 函数名称
+	Param  string         // Param 参数类型的写法，用于错误信息；类型由类型检查后的包比较
+	Result string         // Result 返回值类型 error 或 bool
+	Pos    token.Position // Pos 函数声明的位置
+}
+
+// newTagFunc returns the TagFunc of decl, or nil when decl is not a function
+// with one parameter returning error or bool.
+func newTagFunc(fset *token.FileSet, decl *ast.FuncDecl) *TagFunc {
+	if decl.Recv != nil || decl.Type.TypeParams != nil {
+		return nil
+	}
+	params, results := decl.Type.Params.List, decl.Type.Results
+	if len(params) != 1 || len(params[0].Names) > 1 || results == nil || len(results.List) != 1 || len(results.List[0].Names) > 1 {
+		return nil
+	}
+	result, ok := results.List[0].Type.(*ast.Ident)
+	if !ok || (result.Name != ErrorKeyword && result.Name != "bool") {
+		return nil
+	}
+	return &TagFunc{
+		Name:   decl.Name.Name,
+		Param:  canonicalType(types.ExprString(params[0].Type)),
+		Result: result.Name,
+		Pos:    fset.Position(decl.Pos()),
+	}
+}
+
+var (
+	aliasPattern     = regexp.MustCompile(`\b(byte|rune|any)\b`)
+	interfacePattern = regexp.MustCompile(`interface ?\{\}`)
+	aliases          = map[string]string{"byte": "uint8", "rune": "int32", "any": "interface {}"}
+)
+
+// canonicalType rewrites the predeclared aliases in the type expression s, so
+// that the types written in the source compare equal to the types of reflect.
+func canonicalType(s string) string {
+	s = interfacePattern.ReplaceAllString(s, "interface {}")
+	return aliasPattern.ReplaceAllStringFunc(s, func(alias string) string {
+		return aliases[alias]
+	})
+}
+
+// typeString returns typ as written in the package of parent.
+func typeString(parent, typ reflect.Type) string {
+	if typ.Name() != "" {
+		return typeName(parent, typ)
+	}
+	switch typ.Kind() {
+	case reflect.Ptr:
+		return "*" + typeString(parent, typ.Elem())
+	case reflect.Slice:
+		return "[]" + typeString(parent, typ.Elem())
+	case reflect.Array:
+		return fmt.Sprintf("[%d]%s", typ.Len(), typeString(parent, typ.Elem()))
+	case reflect.Map:
+		return fmt.Sprintf("map[%s]%s", typeString(parent, typ.Key()), typeString(parent, typ.Elem()))
+	}
+	return typ.String()
+}
+
+// ResolveFuncs resolves the functions named by the func rules of the fields of e
+// in res, the parse result of the package of e, and checks that their parameter
+// accepts the field.
+func (e *Entity) ResolveFuncs(res *ParseResult) error {
+	key := e.ParseTag
+	if key == "" {
+		key = DefaultParseTag
+	}
+	for _, n := range e.Fields {
+		for _, tag := range n.Tags {
+			if Operator(tag.Operator) != Func {
+				continue
+			}
+			if err := n.resolveFunc(e.EntityName, tag, res); err != nil {
+				d := &Diagnostic{Msg: fmt.Sprintf("%s.%s: %s", e.EntityName, n.Field, err)}
+				if ft, ok := res.Tags[e.EntityName+"."+n.Field]; ok {
+					d.Pos = ft.RulePos(key, tag)
+				}
+				return d
+			}
+		}
+	}
+	return nil
+}
+
+func (n *Node) resolveFunc(entity string, tag *Tag, res *ParseResult) error {
+	name := fmt.Sprint(tag.Value)
+	fn, ok := res.Funcs[name]
+	if !ok {
+		return fmt.Errorf("func %s: function not found in package %s, want func(%s) error or func(%s) bool", name, res.Pkg, n.GoType, n.GoType)
+	}
+	// 比较类型检查后的类型，导入别名和点导入的写法不同但类型相同
+	pkg, err := res.typesPackage()
+	if err != nil {
+		return fmt.Errorf("func %s: %w", name, err)
+	}
+	param, field := funcParam(pkg, name), fieldType(pkg, entity, n.Field)
+	if param == nil || field == nil {
+		return fmt.Errorf("func %s: no type information of %s.%s", name, entity, n.Field)
+	}
+	switch ptr, isPtr := types.Unalias(field).(*types.Pointer); {
+	case types.Identical(param, field):
+	// 指针字段与其他规则一样解引用后传入
+	case isPtr && types.Identical(param, ptr.Elem()):
+		tag.deref = true
+	default:
+		return fmt.Errorf("func %s: cannot use %s as %s in argument", name, n.GoType, fn.Param)
+	}
+	tag.fn = fn
+	return nil
+}
+
+// funcParam returns the type of the parameter of the function name in pkg.
+func funcParam(pkg *types.Package, name string) types.Type {
+	fn, ok := pkg.Scope().Lookup(name).(*types.Func)
+	if !ok {
+		return nil
+	}
+	params := fn.Type().(*types.Signature).Params()
+	if params.Len() != 1 {
+		return nil
+	}
+	return params.At(0).Type()
+}
+
+// fieldType returns the type of the field of the struct entity in pkg.
+func fieldType(pkg *types.Package, entity, field string) types.Type {
+	obj, ok := pkg.Scope().Lookup(entity).(*types.TypeName)
+	if !ok {
+		return nil
+	}
+	st, ok := obj.Type().Underlying().(*types.Struct)
+	if !ok {
+		return nil
+	}
+	for i := 0; i < st.NumFields(); i++ {
+		if st.Field(i).Name() == field {
+			return st.Field(i).Type()
+		}
+	}
+	return nil
+}
+
+// FuncCall returns the call of the function of the func rule tag on the field.
+func (n *Node) FuncCall(tag *Tag) string {
+	arg := n.Ref()
+	if tag.deref {
+		arg = "*" + arg
+	}
+	return fmt.Sprintf("%s(%s)", strings.TrimSpace(fmt.Sprint(tag.Value)), arg)
+}
+
+// FuncGuard returns the condition under which the func rule tag can dereference the
+// pointer field, or "" when there is no need to check: the function takes the pointer,
+// or an earlier omitempty, required or nonzero rule has already handled nil.
+func (n *Node) FuncGuard(tag *Tag) string {
+	if !tag.deref {
+		return ""
+	}
+	for _, t := range n.Tags {
+		if t == tag {
+			break
+		}
+		switch Operator(t.Operator) {
+		case OmitEmpty, Required, NonZero:
+			return ""
+		}
+	}
+	return n.Ref() + " != nil"
+}
+
+// IsFuncError reports whether tag is a func rule calling a function that returns error.
+func (n *Node) IsFuncError(tag *Tag) bool {
+	return Operator(tag.Operator) == Func && tag.fn != nil && tag.fn.Result == ErrorKeyword
+}
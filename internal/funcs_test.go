@@ -0,0 +1,58 @@
+package internal
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type funcFields struct {
+	Name   string
+	Bytes  []byte
+	Ptr    *int
+	Values map[string][]*Diagnostic
+	Array  [2]rune
+	Any    any
+}
+
+func TestTypeString(t *testing.T) {
+	typ := reflect.TypeOf(funcFields{})
+	want := []string{"string", "[]uint8", "*int", "map[string][]*Diagnostic", "[2]int32", "interface {}"}
+	for i, w := range want {
+		assert.Equal(t, w, typeString(typ, typ.Field(i).Type), typ.Field(i).Name)
+	}
+}
+
+func TestNewTagFunc(t *testing.T) {
+	const src = `package p
+
+func str(s string) error { return nil }
+func bytes(b []byte) bool { return true }
+func anything(v interface{}) error { return nil }
+func noParam() error { return nil }
+func twoParams(a, b string) error { return nil }
+func noResult(s string) {}
+func intResult(s string) int { return 0 }
+func generic[T any](v T) error { return nil }
+func (t T) method(s string) error { return nil }
+`
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "p.go", src, 0)
+	require.NoError(t, err)
+	res := ParseASTFiles(fset, []*ast.File{f})
+
+	got := make(map[string]string, len(res.Funcs))
+	for name, fn := range res.Funcs {
+		got[name] = fn.Param + " " + fn.Result
+	}
+	assert.Equal(t, map[string]string{
+		"str":      "string error",
+		"bytes":    "[]uint8 bool",
+		"anything": "interface {} error",
+	}, got)
+}
@@ -0,0 +1,129 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+)
+
+// helperReceiver 辅助函数的参数名称
+const helperReceiver = "v"
+
+// Helper is a function generated with an entity that validates a nested struct
+// declared in another package, in place of a method on that struct:
+//
+//	func validateOrderAddress(ctx context.Context, v *ext.Address) error
+type Helper struct {
+	Name     string   // Name 函数名称 validate<Entity><Type>
+	TypeName string   // TypeName 结构体在生成的包中的写法，如 ext.Address
+	Packages []string // Packages 函数需要导入的包
+	Fields   []*Node
+}
+
+// SetHelpers decides how the nested structs of e are validated. A struct of the
+// module is validated by its generated method, which is written in the package
+// of the struct. A struct outside the module, or outside the package of e when
+// e.Local is set, is validated by a Helper generated with e; structs outside the
+// module without any rule are skipped.
+func (e *Entity) SetHelpers() error {
+	e.Helpers = nil
+	return e.setHelpers(e.Fields, false, map[string]*Helper{})
+}
+
+func (e *Entity) setHelpers(nodes []*Node, inHelper bool, helpers map[string]*Helper) error {
+	for _, n := range nodes {
+		if n.Elem != nil {
+			if err := e.setHelpers([]*Node{n.Elem}, inHelper, helpers); err != nil {
+				return err
+			}
+		}
+		n.helper = ""
+		if !n.hasNested() || n.Generic {
+			continue
+		}
+		external := n.Package == ""
+		if !inHelper && !external && (!e.Local || n.PkgRelPath == e.PkgRelPath) {
+			continue
+		}
+		key := n.PkgPath + "." + n.EntityName
+		if h, ok := helpers[key]; ok {
+			n.helper = h.Name
+			continue
+		}
+		if n.Recursive || (external && !hasRules(n.Fields)) {
+			continue
+		}
+
+		h := &Helper{
+			Name:     "validate" + e.EntityName + n.EntityName,
+			TypeName: n.EntityName,
+			Packages: []string{n.PkgPath, "errors"},
+			Fields:   n.Fields,
+		}
+		if n.PkgName != "" {
+			h.TypeName = n.PkgName + "." + n.EntityName
+		}
+		for _, other := range e.Helpers {
+			if other.Name == h.Name && n.PkgName != "" {
+				h.Name = "validate" + e.EntityName + strings.ToUpper(n.PkgName[:1]) + n.PkgName[1:] + n.EntityName
+				break
+			}
+		}
+		for _, f := range n.Fields {
+			if err := f.prepareHelperField(n); err != nil {
+				return &Diagnostic{Msg: fmt.Sprintf("%s.%s: %s", n.EntityName, f.Field, err)}
+			}
+			h.Packages = append(h.Packages, f.Packages...)
+		}
+		helpers[key] = h
+		n.helper = h.Name
+		e.Helpers = append(e.Helpers, h)
+		if err := e.setHelpers(n.Fields, true, helpers); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// prepareHelperField prepares the field f of the struct parent for the body of
+// the helper of parent, which is written in another package.
+func (f *Node) prepareHelperField(parent *Node) error {
+	if f.HasTag(Func.String()) {
+		return fmt.Errorf("%s is not supported on structs outside the package", Func)
+	}
+	f.signature.Receiver = helperReceiver
+	if f.Elem != nil {
+		f.Elem.signature.Receiver = helperReceiver
+	}
+	// 与上层结构体同包的类型需要加上包名
+	if f.TypeName != "" && f.PkgPath == parent.PkgPath && !strings.Contains(f.TypeName, ".") {
+		f.TypeName = parent.PkgName + "." + f.TypeName
+	}
+	if f.RealType == "struct" && f.Kind != "ptr" && f.PkgPath != "" && (f.IsRequired() || f.HasTag(OmitEmpty.String())) {
+		f.AddPackages(f.PkgPath)
+	}
+	return nil
+}
+
+// hasRules reports whether any of nodes or of their nested fields has a rule.
+func hasRules(nodes []*Node) bool {
+	for _, n := range nodes {
+		if len(n.Tags) > 0 || hasRules(n.Fields) {
+			return true
+		}
+	}
+	return false
+}
+
+// Helper returns the helper validating the field's struct, or "" when the struct
+// is validated by its method or not at all.
+func (n *Node) Helper() string {
+	return n.helper
+}
+
+// HelperArg returns the argument of the call of the field's helper.
+func (n *Node) HelperArg() string {
+	if n.Kind == "ptr" {
+		return n.Ref()
+	}
+	return "&" + n.Ref()
+}
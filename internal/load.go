@@ -0,0 +1,30 @@
+package internal
+
+import (
+	"fmt"
+	"go/types"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// LoadPackage loads the type information of the package in dir, for the structs
+// that are parsed with ParseTypes instead of reflection.
+func LoadPackage(dir string) (*types.Package, error) {
+	// 从源码进行类型检查，包中的代码可能调用尚未生成的验证方法
+	mode := packages.NeedName | packages.NeedTypes | packages.NeedSyntax | packages.NeedTypesInfo | packages.NeedImports | packages.NeedDeps
+	cfg := &packages.Config{Mode: mode, Dir: dir}
+	pkgs, err := packages.Load(cfg, ".")
+	if err != nil {
+		return nil, err
+	}
+	if len(pkgs) != 1 {
+		return nil, fmt.Errorf("%s: 找到 %d 个包", dir, len(pkgs))
+	}
+	// 类型错误不影响结构体的声明，只有无法解析的包才报错
+	for _, e := range pkgs[0].Errors {
+		if e.Kind != packages.TypeError {
+			return nil, e
+		}
+	}
+	return pkgs[0].Types, nil
+}
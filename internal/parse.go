@@ -8,8 +8,13 @@ import (
 	"go/ast"
 	"go/parser"
 	"go/token"
+	"go/types"
+	"path/filepath"
 	"reflect"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 )
 
 const (
@@ -17,6 +22,8 @@ const (
 	DefaultParseCustomValidator = "// @ext:check"
 	DefaultParsePath            = "// @path:"
 	DefaultParsePackage         = "// @package:"
+	// SkipTag 跳过字段，不解析其规则和嵌套结构体
+	SkipTag = "-"
 )
 
 const (
@@ -31,12 +38,17 @@ type Entity struct {
 	EntityName   string
 	PackageName  string
 	PkgRelPath   string // PkgRelPath the package relative path
+	Source       string // Source 结构体声明所在的文件，相对于工作目录
 	Packages     []string
 	ParseTag     string
 	FileAbsPaths []string
 	CustomFuncs  []*FuncType
 	Invalid      bool
 	Fields       []*Node
+	Signature    Signature // Signature 生成的验证方法的签名
+	Local        bool      // Local 不在结构体所在的包之外生成代码，见 SetHelpers
+	Helpers      []*Helper // Helpers 验证其他包中嵌套结构体的函数
+	TypeParams   []string  // TypeParams 泛型结构体的类型参数名称
 }
 
 type Node struct {
@@ -46,15 +58,32 @@ type Node struct {
 	Packages     []string
 	Kind         string // Kind reflect.kind
 	RealType     string
+	TypeName     string // TypeName the type's name as written in the generated package
+	GoType       string // GoType the field's type as written in the generated package, such as []*Address
+	Comparable   bool
 	Package      string
 	PkgRelPath   string   // PkgRelPath package relative path
+	PkgPath      string   // PkgPath 字段类型所在包的导入路径，包括模块之外的包
+	PkgName      string   // PkgName 字段类型所在包的名称
 	FileAbsPaths []string // Fields 子节点
 	Fields       []*Node
+	Recursive    bool     // Recursive 字段类型的字段又引用了所在的结构体，与路径无关；路径上已解析的类型不再展开 Fields
+	Embedded     bool     // Embedded 嵌入字段，Field 为类型名
+	Generic      bool     // Generic 字段类型是泛型结构体的实例，调用其验证方法，代码在声明所在的包中生成
+	Elem         *Node    // Elem 切片或数组的元素，只在 dive 规则中解析
+	constraint   []string // constraint 类型参数的约束中可以调用的验证方法
+	dive         bool     // dive 节点是 Elem，不是结构体的字段
+	signature    Signature
+	helper       string // helper 验证字段的辅助函数，见 Entity.SetHelpers
+	typ          any    // typ 字段的结构体类型，reflect.Type 或 *types.Named，见 Entity.ParseNested
 }
 
 type Tag struct {
 	Operator string //Operator  操作符 gt, lt, gte ,email....
 	Value    any    // Value 对应的值
+	fn       *TagFunc
+	deref    bool // deref func 规则的函数接受指针字段指向的值
+	offset   int  // offset 规则在标签值中的字节偏移，用于定位诊断
 }
 
 func NewEntity() *Entity {
@@ -101,6 +130,15 @@ func (e *Entity) SetTag(tag string) {
 	e.ParseTag = tag
 }
 
+// TypeName returns the type of e as written in the receiver of its methods, with
+// the type parameters of a generic struct, such as Page[T].
+func (e *Entity) TypeName() string {
+	if len(e.TypeParams) == 0 {
+		return e.EntityName
+	}
+	return e.EntityName + "[" + strings.Join(e.TypeParams, ", ") + "]"
+}
+
 // Parser parses entity.
 func (e *Entity) Parser(entity any) error {
 	if entity == nil {
@@ -117,15 +155,23 @@ func (e *Entity) Parser(entity any) error {
 	}
 
 	e.EntityName = typ.Name()
+	// 反射只能得到泛型结构体的实例，如 Page[int]
+	if strings.Contains(e.EntityName, "[") {
+		return fmt.Errorf("generic struct %s: parse its declaration with go/types, see pkg.Decl", e.EntityName)
+	}
 
-	// 不处理main包的验证
+	// 其他程序不能导入 main 包，其中的结构体只能静态解析
 	if typ.PkgPath() == "main" {
-		return nil
+		return fmt.Errorf("struct %s of package main: parse its declaration with go/types, see pkg.Decl", e.EntityName)
 	}
 	relPath, pkg, _ := getRelPathAndPkg(typ.PkgPath())
 	e.PkgRelPath = relPath
 	e.PackageName = pkg
-	return parseField(&e.Fields, typ, e.ParseTag)
+	return parseField(&e.Fields, typ, &parseContext{
+		tag:     e.ParseTag,
+		parents: map[any]struct{}{},
+		sources: NewSourceIndex(),
+	})
 }
 
 // getRelPathAndPkg returns relative path and package name.
@@ -141,7 +187,7 @@ func getRelPathAndPkg(pkg string) (string, string, error) {
 	if err != nil {
 		return "", "", err
 	}
-	if len(pkg) < len(mod) {
+	if !strings.HasPrefix(pkg, mod+"/") {
 		return "", "", errors.New("invalid pkg path")
 	}
 	rel := pkg[len(mod)+1:]
@@ -172,10 +218,33 @@ func (n *Node) AddFileAbsPaths(absPaths ...string) {
 	}
 }
 
-func parseField(root *[]*Node, t reflect.Type, tag string) error {
+// parseContext is the state shared by parseField while parsing one entity.
+type parseContext struct {
+	tag string
+	// parents 当前路径上正在解析的结构体，用于检测递归类型
+	parents map[any]struct{}
+	sources *SourceIndex
+	// report 不为空时，字段的错误交给 report 处理并跳过该字段，而不是中断解析
+	report func(error)
+}
+
+// fail returns the error of a field located in the source, or reports it when ctx.report is set.
+func (ctx *parseContext) fail(pkgPath, typeName, field string, err error) error {
+	err = ctx.sources.fieldError(pkgPath, typeName, field, ctx.tag, err)
+	if ctx.report != nil {
+		ctx.report(err)
+		return nil
+	}
+	return err
+}
+
+// parseField parses the fields of t into root.
+func parseField(root *[]*Node, t reflect.Type, ctx *parseContext) error {
+	ctx.parents[t] = struct{}{}
+	defer delete(ctx.parents, t)
 	for i := 0; i < t.NumField(); i++ {
 		field := t.Field(i)
-		if !field.IsExported() {
+		if !field.IsExported() || field.Tag.Get(ctx.tag) == SkipTag {
 			continue
 		}
 
@@ -186,50 +255,183 @@ func parseField(root *[]*Node, t reflect.Type, tag string) error {
 			i++
 			fT = fT.Elem()
 			if i >= 2 {
-				return errors.New(t.Name() + "." + field.Name + "只能使用一级指针")
+				break
 			}
 		}
+		if i >= 2 {
+			if err := ctx.fail(t.PkgPath(), t.Name(), field.Name, errors.New("只能使用一级指针")); err != nil {
+				return err
+			}
+			continue
+		}
 
 		curNode := &Node{}
 		curNode.Field = field.Name
+		curNode.Embedded = field.Anonymous
+		curNode.Kind = field.Type.Kind().String()
+		curNode.GoType = typeString(t, field.Type)
+		curNode.setTags(field.Tag.Get(ctx.tag))
+		subTyp, err := curNode.setType(t, field.Type, ctx)
+		if err != nil {
+			return err
+		}
+		if curNode.HasTag(Dive.String()) && (subTyp.Kind() == reflect.Slice || subTyp.Kind() == reflect.Array) {
+			elem := &Node{Field: field.Name + "[i]", Kind: subTyp.Elem().Kind().String(), dive: true}
+			elem.GoType = typeString(t, subTyp.Elem())
+			if _, err := elem.setType(t, subTyp.Elem(), ctx); err != nil {
+				return err
+			}
+			curNode.Elem = elem
+		}
+		if err := curNode.finish(subTyp.PkgPath(), t.PkgPath()); err != nil {
+			if err := ctx.fail(t.PkgPath(), t.Name(), field.Name, err); err != nil {
+				return err
+			}
+			continue
+		}
+		*root = append(*root, curNode)
+	}
+	return nil
+}
 
-		subTyp := field.Type
-		curNode.Kind = subTyp.Kind().String()
+// setType sets the type information of n, a field or an element of struct t, from
+// typ, and parses the fields of its struct type. It returns typ without the pointer.
+func (n *Node) setType(t, typ reflect.Type, ctx *parseContext) (reflect.Type, error) {
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	n.RealType = typ.Kind().String()
+	n.TypeName = typeName(t, typ)
+	n.Comparable = typ.Comparable()
 
-		if tags, err := parseTag(field.Tag.Get(tag)); err == nil {
-			curNode.Tags = tags
-			for _, tag := range tags {
-				if _, ok := regexpRoles[Operator(tag.Operator)]; ok {
-					curNode.AddPackages("regexp")
-				}
-				curNode.AddPackages("errors")
+	relPath, pkg, _ := getRelPathAndPkg(typ.PkgPath())
+
+	n.PkgRelPath = relPath
+	n.Package = pkg
+	n.PkgPath = typ.PkgPath()
+	if typ.PkgPath() != "" {
+		n.PkgName = strings.TrimSuffix(typ.String(), "."+typ.Name())
+	}
+	n.EntityName = typ.Name()
+	if typ.Kind() == reflect.Struct {
+		n.Recursive = reaches(typ, t, ctx.tag, map[reflect.Type]bool{})
+	}
+	if name, _, ok := strings.Cut(typ.Name(), "["); ok {
+		// 泛型结构体的实例不能写出类型名，也不按实例的字段生成代码
+		n.EntityName = name
+		n.TypeName = ""
+		n.Generic = true
+	} else if typ.Kind() == reflect.Struct {
+		n.typ = typ
+		if _, ok := ctx.parents[typ]; !ok {
+			n.Fields = make([]*Node, 0, 10)
+			if err := parseField(&n.Fields, typ, ctx); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return typ, nil
+}
+
+// reaches reports whether the struct typ is target or leads to it through its
+// fields, following pointers and the elements of dive rules like parseField.
+func reaches(typ, target reflect.Type, tag string, seen map[reflect.Type]bool) bool {
+	if typ == target {
+		return true
+	}
+	if seen[typ] {
+		return false
+	}
+	seen[typ] = true
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if !field.IsExported() || field.Tag.Get(tag) == SkipTag {
+			continue
+		}
+		next := []reflect.Type{field.Type}
+		if k := field.Type.Kind(); (k == reflect.Slice || k == reflect.Array) && hasDive(field.Tag.Get(tag)) {
+			next = append(next, field.Type.Elem())
+		}
+		for _, ft := range next {
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct && reaches(ft, target, tag, seen) {
+				return true
 			}
 		}
+	}
+	return false
+}
 
-		if subTyp.Kind() == reflect.Ptr {
-			subTyp = subTyp.Elem()
+// hasDive reports whether the tag holds the dive rule.
+func hasDive(tag string) bool {
+	tags, _ := parseTag(tag)
+	for _, t := range tags {
+		if Operator(t.Operator) == Dive {
+			return true
 		}
-		curNode.RealType = subTyp.Kind().String()
+	}
+	return false
+}
 
-		relPath, pkg, _ := getRelPathAndPkg(subTyp.PkgPath())
+// ParseNested parses the struct type of the nested field n as an entity of its own,
+// like Parser or ParseTypes, so that its code does not depend on the struct it was
+// reached from.
+func (e *Entity) ParseNested(n *Node) error {
+	switch typ := n.typ.(type) {
+	case reflect.Type:
+		return e.Parser(reflect.Zero(typ).Interface())
+	case *types.Named:
+		return e.ParseTypes(typ, nil, nil)
+	}
+	return fmt.Errorf("%s: invalid entity", n.EntityName)
+}
 
-		curNode.PkgRelPath = relPath
-		curNode.Package = pkg
-		curNode.EntityName = subTyp.Name()
-		if subTyp.Kind() == reflect.Struct {
-			curNode.Fields = make([]*Node, 0, 10)
-			err := parseField(&curNode.Fields, subTyp, tag)
-			if err != nil {
-				return err
-			}
+// setTags parses tag into the rules of the field and records the packages they need.
+func (n *Node) setTags(tag string) {
+	tags, err := parseTag(tag)
+	if err != nil {
+		return
+	}
+	n.Tags = tags
+	for _, tag := range tags {
+		if _, ok := regexpRoles[Operator(tag.Operator)]; ok || Operator(tag.Operator) == Regexp {
+			n.AddPackages("regexp")
 		}
-		*root = append(*root, curNode)
+		if Operator(tag.Operator) == EqFold || Operator(tag.Operator) == NeFold {
+			n.AddPackages("strings")
+		}
+		n.AddPackages("errors")
+	}
+}
+
+// finish checks the rules of the field once its type is known. typPkgPath is the
+// package of the field's type and parentPkgPath the package of the enclosing struct.
+func (n *Node) finish(typPkgPath, parentPkgPath string) error {
+	if err := checkTags(n); err != nil {
+		return err
+	}
+	// 零值比较需要引用其他包中的结构体
+	if n.Kind != "ptr" && n.RealType == "struct" && typPkgPath != parentPkgPath &&
+		(n.IsRequired() || n.HasTag(OmitEmpty.String())) {
+		n.AddPackages(typPkgPath)
 	}
 	return nil
 }
 
+// typeName returns the name of typ as written in the package of parent,
+// or "" when typ is not a named type.
+func typeName(parent, typ reflect.Type) string {
+	if typ.Name() == "" || typ.PkgPath() == "" || typ.PkgPath() == parent.PkgPath() {
+		return typ.Name()
+	}
+	return typ.String()
+}
+
 // parseTag returns a Tag pointer slice.
 func parseTag(tag string) ([]*Tag, error) {
+	offset := len(tag) - len(strings.TrimLeft(tag, ";"))
 	tag = strings.Trim(tag, ";")
 	if tag == "" {
 		return nil, errors.New("empty tag")
@@ -240,72 +442,180 @@ func parseTag(tag string) ([]*Tag, error) {
 	//}
 	tags := make([]*Tag, 0, 4)
 	for _, t := range _tags {
-		segs := strings.Split(strings.Trim(t, " "), " ")
-		newTag := &Tag{}
+		rule := strings.Trim(t, " ")
+		segs := strings.SplitN(rule, " ", 2)
+		newTag := &Tag{offset: offset + strings.Index(t, rule)}
+		offset += len(t) + 1
 		// notEmpty
 		if len(segs) == 1 {
 			newTag.Operator = segs[0]
 		}
-		// gt 0
+		// gt 0, eq "hello world"
 		if len(segs) == 2 {
 			newTag.Operator = segs[0]
-			newTag.Value = segs[1]
+			newTag.Value = strings.Trim(segs[1], " ")
 		}
 		tags = append(tags, newTag)
 	}
 	return tags, nil
 }
 
+// FuncType is a custom validator, a method marked with DefaultParseCustomValidator.
 type FuncType struct {
-	Name    string    // 函数名称
-	Recv    *RecvType // 函数接受者
-	Returns *Returns  // 返回值
+	Name    string         // 函数名称
+	Recv    *RecvType      // 函数接受者，函数没有接受者时为 nil
+	Returns *Returns       // 返回值
+	Context bool           // Context 函数接收 context.Context 参数
+	Pos     token.Position // Pos 函数声明的位置
+	Err     error          // Err 签名不正确时的错误，带有位置
 }
+
+const customFuncSignature = "自定义函数签名不正确，签名应为：func() error 或 func(context.Context) error"
+
 type Returns struct {
 	Name string
 	Kind string
 }
 type RecvType struct {
-	Name  string
-	Type  string // 接受者类型 值类型或者指针类型
-	Value string // 接受者
+	Name  string // 接受者名称
+	Value string // 接受者的类型名称，指针接受者去掉 *
 }
 
 func ParseFile(srcFiles []string) (*ParseResult, error) {
-	var res ParseResult
-	fts := make([]*FuncType, 0, 10)
-	res.Annotations = make(map[string][]string, 10)
-	res.Entities = make([]string, 0, 10)
+	fset := token.NewFileSet()
+	files := make([]*ast.File, 0, len(srcFiles))
 	for _, src := range srcFiles {
-		fset := token.NewFileSet()
 		f, err := parser.ParseFile(fset, src, nil, parser.ParseComments)
 		if err != nil {
 			return nil, err
 		}
-		v := &SingleFileVisitor{}
+		files = append(files, f)
+	}
+	res := ParseASTFiles(fset, files)
+	if len(srcFiles) > 0 {
+		res.dir = filepath.Dir(srcFiles[0])
+	}
+	return res, nil
+}
+
+// ParseASTFiles collects the entities, annotations and custom validators of parsed files.
+func ParseASTFiles(fset *token.FileSet, files []*ast.File) *ParseResult {
+	var res ParseResult
+	fts := make([]*FuncType, 0, 10)
+	res.Annotations = make(map[string][]string, 10)
+	res.Entities = make([]string, 0, 10)
+	res.Positions = make(map[string]token.Position, 10)
+	res.Tags = make(map[string]*FieldTag, 10)
+	res.Funcs = make(map[string]*TagFunc, 10)
+	res.Generics = make(map[string]bool, 2)
+	for _, f := range files {
+		v := &SingleFileVisitor{fset: fset}
 		ast.Walk(v, f)
 		fts = append(fts, v.f.ft...)
 		for key, val := range v.f.annotations {
 			res.Annotations[key] = val
 		}
+		for key, val := range v.f.positions {
+			res.Positions[key] = val
+		}
+		for key, val := range v.f.tags {
+			res.Tags[key] = val
+		}
+		for _, fn := range v.f.funcs {
+			res.Funcs[fn.Name] = fn
+		}
+		for key, val := range v.f.generics {
+			res.Generics[key] = val
+		}
 		res.Entities = append(res.Entities, v.f.entities...)
 		res.Pkg = v.pkg
 	}
 	res.FuncType = fts
-	return &res, nil
+	return &res
 }
 
 type ParseResult struct {
 	FuncType    []*FuncType
 	Annotations map[string][]string
 	Entities    []string
+	Positions   map[string]token.Position // Positions 结构体声明的位置
+	Tags        map[string]*FieldTag      // Tags 结构体字段的标签，键为 Entity.Field
+	Funcs       map[string]*TagFunc       // Funcs 可以在 func 规则中使用的函数
+	Generics    map[string]bool           // Generics 泛型结构体，反射不能描述，需要静态分析
 	Pkg         string
+	// Types 类型检查后的包，func 规则用其比较函数参数与字段的类型；为 nil 时从解析的文件所在目录加载
+	Types *types.Package
+
+	dir     string    // dir 解析的文件所在的目录
+	load    sync.Once // load 加载 Types 一次
+	loadErr error
+}
+
+// typesPackage returns the type-checked package of the parsed files, loading it from
+// their directory the first time it is needed when Types is not set.
+func (p *ParseResult) typesPackage() (*types.Package, error) {
+	p.load.Do(func() {
+		switch {
+		case p.Types != nil:
+		case p.dir == "":
+			p.loadErr = fmt.Errorf("package %s: no type information", p.Pkg)
+		default:
+			p.Types, p.loadErr = LoadPackage(p.dir)
+		}
+	})
+	return p.Types, p.loadErr
 }
 
 func (p *ParseResult) GetEntities() []string {
 	return p.Entities
 }
 
+// GetTaggedEntities returns the entities with at least one field tagged with key, in declaration order.
+func (p *ParseResult) GetTaggedEntities(key string) []string {
+	entities := make([]string, 0, len(p.Entities))
+	for _, entity := range p.Entities {
+		for name, ft := range p.Tags {
+			if !strings.HasPrefix(name, entity+".") {
+				continue
+			}
+			lit, err := strconv.Unquote(ft.Lit)
+			if err != nil {
+				continue
+			}
+			if _, ok := reflect.StructTag(lit).Lookup(key); ok {
+				entities = append(entities, entity)
+				break
+			}
+		}
+	}
+	return entities
+}
+
+// CustomFuncs returns the custom validators of the struct entity in the order they
+// are called: by file name, then in declaration order. It returns the error of the
+// first custom validator of entity, or of a function that is not a method, whose
+// signature is invalid.
+func (p *ParseResult) CustomFuncs(entity string) ([]*FuncType, error) {
+	fts := make([]*FuncType, 0, 2)
+	for _, ft := range p.FuncType {
+		if ft.Recv != nil && ft.Recv.Value != entity {
+			continue
+		}
+		if ft.Err != nil {
+			return nil, ft.Err
+		}
+		fts = append(fts, ft)
+	}
+	sort.SliceStable(fts, func(i, j int) bool {
+		a, b := fts[i].Pos, fts[j].Pos
+		if a.Filename != b.Filename {
+			return filepath.Base(a.Filename) < filepath.Base(b.Filename)
+		}
+		return a.Offset < b.Offset
+	})
+	return fts, nil
+}
+
 // GetPath 获取注解自定义路径
 func (p *ParseResult) GetPath(entityName string) string {
 	ans, ok := p.Annotations[entityName]
@@ -341,30 +651,139 @@ func (p *ParseResult) GetPackage(entityName string) string {
 }
 
 type SingleFileVisitor struct {
-	f   *fileVisitor
-	pkg string // package name
+	f    *fileVisitor
+	pkg  string // package name
+	fset *token.FileSet
 }
 
 func (s *SingleFileVisitor) Visit(node ast.Node) (w ast.Visitor) {
 	n, ok := node.(*ast.File)
 	if ok {
 		s.pkg = n.Name.Name
-		s.f = &fileVisitor{ft: make([]*FuncType, 0, 3), annotations: map[string][]string{}, entities: make([]string, 0, 10)}
+		s.f = &fileVisitor{
+			context:     importName(n, "context"),
+			ft:          make([]*FuncType, 0, 3),
+			funcs:       make([]*TagFunc, 0, 3),
+			annotations: map[string][]string{},
+			entities:    make([]string, 0, 10),
+			positions:   map[string]token.Position{},
+			tags:        map[string]*FieldTag{},
+			generics:    map[string]bool{},
+			fset:        s.fset,
+		}
 		return s.f
 	}
 	return s
 }
 
 type fileVisitor struct {
+	context     string              // context 文件中 context 包的名称，没有导入时为空
 	ft          []*FuncType         // 方法注解 自定义验证方法
+	funcs       []*TagFunc          // funcs 可以在 func 规则中使用的函数
 	annotations map[string][]string // 类型注解
 	entities    []string
+	positions   map[string]token.Position
+	tags        map[string]*FieldTag
+	generics    map[string]bool
+	fset        *token.FileSet
+}
+
+// customFunc returns the custom validator declared by decl. A declaration that cannot
+// be called by the generated code is returned with Err set.
+func (f *fileVisitor) customFunc(decl *ast.FuncDecl) *FuncType {
+	ft := &FuncType{Name: decl.Name.Name, Pos: f.fset.Position(decl.Name.Pos())}
+	name := ft.Name
+	invalid := func(pos token.Pos, msg string) *FuncType {
+		ft.Err = &Diagnostic{Pos: f.fset.Position(pos), Msg: name + ": " + msg}
+		return ft
+	}
+
+	// 接受者
+	if decl.Recv != nil && len(decl.Recv.List) == 1 {
+		field := decl.Recv.List[0]
+		recv := &RecvType{}
+		typ := field.Type
+		if star, ok := typ.(*ast.StarExpr); ok {
+			typ = star.X
+		}
+		typ = genericType(typ)
+		if ident, ok := typ.(*ast.Ident); ok {
+			recv.Value = ident.Name
+			if len(field.Names) > 0 {
+				recv.Name = field.Names[0].Name
+			}
+			ft.Recv = recv
+			name = recv.Value + "." + name
+		}
+	}
+	if ft.Recv == nil {
+		return invalid(decl.Name.Pos(), DefaultParseCustomValidator+" 只能用于结构体的方法")
+	}
+
+	// 参数
+	if params := decl.Type.Params.List; len(params) > 0 {
+		if !f.isContext(params) {
+			return invalid(decl.Type.Params.Pos(), customFuncSignature)
+		}
+		ft.Context = true
+	}
+
+	// 返回值，可以是命名的 error
+	results := decl.Type.Results
+	if results == nil {
+		return invalid(decl.Type.Params.End(), customFuncSignature)
+	}
+	if len(results.List) != 1 || len(results.List[0].Names) > 1 {
+		return invalid(results.Pos(), customFuncSignature)
+	}
+	ret, ok := results.List[0].Type.(*ast.Ident)
+	if !ok || ret.Name != ErrorKeyword {
+		return invalid(results.Pos(), customFuncSignature)
+	}
+	ft.Returns = &Returns{Kind: ret.Name}
+	if names := results.List[0].Names; len(names) > 0 {
+		ft.Returns.Name = names[0].Name
+	}
+	return ft
+}
+
+// isContext reports whether params is a single context.Context parameter.
+func (f *fileVisitor) isContext(params []*ast.Field) bool {
+	if f.context == "" || len(params) != 1 || len(params[0].Names) > 1 {
+		return false
+	}
+	sel, ok := params[0].Type.(*ast.SelectorExpr)
+	if !ok || sel.Sel.Name != "Context" {
+		return false
+	}
+	x, ok := sel.X.(*ast.Ident)
+	return ok && x.Name == f.context
+}
+
+// importName returns the name the package at path is imported with in file, or "" when
+// the file does not import it.
+func importName(file *ast.File, path string) string {
+	for _, spec := range file.Imports {
+		if p, err := strconv.Unquote(spec.Path.Value); err != nil || p != path {
+			continue
+		}
+		if spec.Name != nil {
+			if spec.Name.Name == "_" || spec.Name.Name == "." {
+				return ""
+			}
+			return spec.Name.Name
+		}
+		return path[strings.LastIndex(path, "/")+1:]
+	}
+	return ""
 }
 
 func (f *fileVisitor) Visit(node ast.Node) (w ast.Visitor) {
 	typ, ok := node.(*ast.FuncDecl)
 	if ok {
-		var ft FuncType
+		if fn := newTagFunc(f.fset, typ); fn != nil {
+			f.funcs = append(f.funcs, fn)
+		}
 		//注解 // ext:check
 		if typ.Doc == nil {
 			return f
@@ -378,62 +797,7 @@ func (f *fileVisitor) Visit(node ast.Node) (w ast.Visitor) {
 		if !flag {
 			return f
 		}
-
-		// 函数名
-		ft.Name = typ.Name.Name
-
-		if typ.Type.Params.List != nil {
-			fmt.Println("自定义函数签名不正确，签名应为：func() error")
-			return f
-		}
-
-		// 返回值
-		if typ.Type.Results != nil && len(typ.Type.Results.List) != 1 {
-			fmt.Println("自定义函数签名不正确，签名应为：func() error")
-			return f
-		}
-		if typ.Type.Results == nil {
-			return f
-		}
-
-		for _, field := range typ.Type.Results.List {
-			var ret Returns
-			retTyp, ok := field.Type.(*ast.Ident)
-			if ok {
-				if retTyp.Name != ErrorKeyword {
-					return f
-				}
-				if len(field.Names) > 0 {
-					ret.Name = field.Names[0].Name
-				}
-				ret.Kind = retTyp.Name
-				ft.Returns = &ret
-			}
-			//retStarTyp, ok := field.Type.(*ast.StarExpr)
-		}
-
-		// 接受者
-		if typ.Recv == nil {
-			return f
-		}
-		for _, field := range typ.Recv.List {
-			var recv RecvType
-			ValRecv, ok := field.Type.(*ast.Ident)
-			if ok {
-				recv.Value = ValRecv.Name
-				ft.Recv = &recv
-			}
-			PtrRecv, ok := field.Type.(*ast.StarExpr)
-			if ok {
-				x, xok := PtrRecv.X.(*ast.Ident)
-				if xok {
-					recv.Value = x.Name
-					ft.Recv = &recv
-				}
-			}
-		}
-
-		f.ft = append(f.ft, &ft)
+		f.ft = append(f.ft, f.customFunc(typ))
 	}
 
 	gTyp, ok := node.(*ast.GenDecl)
@@ -444,9 +808,13 @@ func (f *fileVisitor) Visit(node ast.Node) (w ast.Visitor) {
 				s, ok := spec.(*ast.TypeSpec)
 				if ok {
 					entityName = s.Name.Name
-					_, isStructTyp := s.Type.(*ast.StructType)
+					structTyp, isStructTyp := s.Type.(*ast.StructType)
 					if isStructTyp {
 						f.entities = append(f.entities, entityName)
+						if s.TypeParams != nil {
+							f.generics[entityName] = true
+						}
+						f.addPositions(entityName, s, structTyp)
 						comments := make([]string, 0, 2)
 						if gTyp.Doc != nil {
 							for _, comment := range gTyp.Doc.List {
@@ -466,3 +834,53 @@ func (f *fileVisitor) Visit(node ast.Node) (w ast.Visitor) {
 	}
 	return f
 }
+
+// addPositions records the position of the struct and the tags of its fields.
+func (f *fileVisitor) addPositions(entityName string, spec *ast.TypeSpec, typ *ast.StructType) {
+	if f.fset == nil {
+		return
+	}
+	f.positions[entityName] = f.fset.Position(spec.Pos())
+	for _, field := range typ.Fields.List {
+		if field.Tag == nil {
+			continue
+		}
+		ft := &FieldTag{Pos: f.fset.Position(field.Tag.Pos()), Lit: field.Tag.Value}
+		for _, name := range fieldNames(field) {
+			f.tags[entityName+"."+name] = ft
+		}
+	}
+}
+
+// fieldNames returns the names of a struct field, including the type name of an embedded field.
+func fieldNames(field *ast.Field) []string {
+	names := make([]string, 0, len(field.Names))
+	for _, name := range field.Names {
+		names = append(names, name.Name)
+	}
+	if len(names) > 0 {
+		return names
+	}
+	typ := field.Type
+	if star, ok := typ.(*ast.StarExpr); ok {
+		typ = star.X
+	}
+	switch x := genericType(typ).(type) {
+	case *ast.Ident:
+		return []string{x.Name}
+	case *ast.SelectorExpr:
+		return []string{x.Sel.Name}
+	}
+	return nil
+}
+
+// genericType returns the generic type of an instantiation such as Page[T], or typ.
+func genericType(typ ast.Expr) ast.Expr {
+	switch x := typ.(type) {
+	case *ast.IndexExpr:
+		return x.X
+	case *ast.IndexListExpr:
+		return x.X
+	}
+	return typ
+}
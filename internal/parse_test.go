@@ -1,9 +1,15 @@
 package internal
 
 import (
+	"SJT/struct-validate/test_data/diag"
 	"errors"
 	"fmt"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strings"
 	"testing"
 )
 
@@ -97,3 +103,286 @@ type Address struct {
 	Province string
 	City     string
 }
+
+func TestParserOmitEmpty(t *testing.T) {
+	type Unsupported struct {
+		Items struct{ Ids []int } `check:"omitempty"`
+	}
+	err := NewEntity().Parser(Unsupported{})
+	assert.EqualError(t, err, "Unsupported.Items: omitempty is not supported on struct")
+
+	type Duplicated struct {
+		Email string `check:"omitempty;omitempty;email"`
+	}
+	err = NewEntity().Parser(Duplicated{})
+	assert.EqualError(t, err, "Duplicated.Email: duplicate omitempty modifier")
+
+	e := NewEntity()
+	assert.NoError(t, e.Parser(struct {
+		Email string `check:"omitempty;email"`
+		Id    *int   `check:"omitempty;gt 0"`
+	}{}))
+}
+
+func TestParserRequired(t *testing.T) {
+	type Unsupported struct {
+		Items struct{ Ids []int } `check:"required"`
+	}
+	err := NewEntity().Parser(Unsupported{})
+	assert.EqualError(t, err, "Unsupported.Items: required is not supported on struct")
+
+	e := NewEntity()
+	assert.NoError(t, e.Parser(Nested{}))
+	assert.Equal(t, "t.Address == (Address{})", e.Fields[4].ZeroExp())
+	assert.Equal(t, "t.Addr == nil", e.Fields[5].ZeroExp())
+	assert.Equal(t, `t.Name == ""`, e.Fields[1].ZeroExp())
+	assert.Equal(t, "t.Id == 0", e.Fields[0].ZeroExp())
+}
+
+func TestParserNumeric(t *testing.T) {
+	type Numbers struct {
+		A int16   `check:"gt 0"`
+		B uint16  `check:"lt 100"`
+		C uintptr `check:"ne 0"`
+	}
+	e := NewEntity()
+	assert.NoError(t, e.Parser(Numbers{}))
+	tag := &Tag{}
+	assert.Equal(t, "t.A <= 0", tag.GetExp("A", "", "gt", "0", e.Fields[0].RealType))
+	assert.Equal(t, "t.B >= 100", tag.GetExp("B", "", "lt", "100", e.Fields[1].RealType))
+	assert.Equal(t, "t.C == 0", tag.GetExp("C", "", "ne", "0", e.Fields[2].RealType))
+
+	type Overflow struct {
+		A uint8 `check:"gt -1"`
+	}
+	assert.EqualError(t, NewEntity().Parser(Overflow{}), "Overflow.A: gt -1: constant -1 overflows uint8")
+}
+
+func TestParserComparison(t *testing.T) {
+	tests := []struct {
+		name    string
+		entity  any
+		wantErr string
+	}{
+		{
+			name: "string order without lexical",
+			entity: struct {
+				Code string `check:"lt n"`
+			}{},
+			wantErr: ".Code: lt n: ordering strings requires the lexical modifier",
+		},
+		{
+			name: "bool order",
+			entity: struct {
+				Ok bool `check:"gt false"`
+			}{},
+			wantErr: ".Ok: gt false: bool is not ordered",
+		},
+		{
+			name: "invalid bool",
+			entity: struct {
+				Ok bool `check:"eq yes"`
+			}{},
+			wantErr: ".Ok: eq yes: invalid bool constant yes",
+		},
+		{
+			name: "eqfold on int",
+			entity: struct {
+				Id int `check:"eqfold 1"`
+			}{},
+			wantErr: ".Id: eqfold is not supported on int",
+		},
+		{
+			name: "quoted string",
+			entity: struct {
+				Name string `check:"eq \"a b\";lexical;lte z"`
+			}{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := NewEntity().Parser(tt.entity)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
+
+type TreeNode struct {
+	Value int `check:"gt 0"`
+	Next  *TreeNode
+	Peer  *Peer
+}
+
+type Peer struct {
+	Node *TreeNode `check:"required"`
+}
+
+func TestParserRecursive(t *testing.T) {
+	e := NewEntity()
+	assert.NoError(t, e.Parser(TreeNode{}))
+
+	next := e.Fields[1]
+	assert.True(t, next.Recursive)
+	assert.Nil(t, next.Fields)
+	assert.True(t, next.HasValidator())
+
+	// Peer 引用了 TreeNode，无论从哪个结构体解析都是递归字段
+	peer := e.Fields[2]
+	assert.True(t, peer.Recursive)
+	assert.NotNil(t, peer.Fields)
+	assert.True(t, peer.Fields[0].Recursive)
+	assert.True(t, peer.Fields[0].HasValidator())
+
+	sub := NewEntity()
+	assert.NoError(t, sub.ParseNested(peer))
+	assert.Equal(t, "Peer", sub.EntityName)
+	assert.True(t, sub.Fields[0].Recursive)
+	assert.NotNil(t, sub.Fields[0].Fields)
+}
+
+func TestParserDive(t *testing.T) {
+	type Unsupported struct {
+		Ids []int `check:"dive"`
+	}
+	err := NewEntity().Parser(Unsupported{})
+	assert.EqualError(t, err, "Unsupported.Ids: dive is not supported on []int: the elements have no validator")
+
+	e := NewEntity()
+	require.NoError(t, e.Parser(struct {
+		Addrs []*Address `check:"max 10;dive"`
+		Items [2]Address `check:"dive"`
+	}{}))
+	addrs := e.Fields[0].Elem
+	assert.Equal(t, "t.Addrs[i]", addrs.Ref())
+	assert.True(t, addrs.HasValidator())
+	assert.True(t, addrs.ValidatesNestedNotNil())
+	assert.True(t, e.Fields[1].Elem.ValidatesNested())
+	assert.Equal(t, "len(t.Addrs) >= 10", e.Fields[0].Exp(e.Fields[0].Tags[0]))
+}
+
+func TestParserPosition(t *testing.T) {
+	tests := []struct {
+		name    string
+		entity  any
+		wantPos string
+		wantMsg string
+	}{
+		{
+			name:    "overflow",
+			entity:  diag.Overflow{},
+			wantPos: "test_data/diag/diag.go:6:28",
+			wantMsg: "Overflow.Age: gt -1: constant -1 overflows uint8",
+		},
+		{
+			name:    "unordered",
+			entity:  diag.Unordered{},
+			wantPos: "test_data/diag/diag.go:11:31",
+			wantMsg: "Unordered.Code: lt n: ordering strings requires the lexical modifier",
+		},
+		{
+			name:    "misspelled",
+			entity:  diag.Misspelled{},
+			wantPos: "test_data/diag/diag.go:16:29",
+			wantMsg: `Misspelled.Email: unknown operator "mail"`,
+		},
+		{
+			name:    "prefixed key",
+			entity:  diag.Prefixed{},
+			wantPos: "test_data/diag/diag.go:21:49",
+			wantMsg: `Prefixed.Email: unknown operator "mail"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := NewEntity().Parser(tt.entity)
+			var d *Diagnostic
+			require.ErrorAs(t, err, &d)
+			assert.Equal(t, tt.wantMsg, d.Msg)
+			assert.True(t, strings.HasSuffix(d.Pos.String(), tt.wantPos), d.Pos.String())
+			assert.True(t, strings.HasSuffix(err.Error(), tt.wantPos+": "+tt.wantMsg), err.Error())
+		})
+	}
+}
+
+func TestParseCustomFuncs(t *testing.T) {
+	const src = `package p
+
+import ctxpkg "context"
+
+type T struct{}
+
+// @ext:check
+func (t *T) withContext(ctx ctxpkg.Context) error { return nil }
+
+// @ext:check
+func (T) named() (err error) { return nil }
+
+// @ext:check
+func (t T) plain() error { return nil }
+`
+	const bad = `package p
+
+import ctxpkg "context"
+
+type U struct{}
+
+// @ext:check
+func (u U) wrongParam(s string) error { return nil }
+
+// @ext:check
+func (u U) twoContexts(a, b ctxpkg.Context) error { return nil }
+
+// @ext:check
+func (u U) noResult() {}
+
+// @ext:check
+func (u U) boolResult() bool { return true }
+
+// @ext:check
+func function() error { return nil }
+`
+	fset := token.NewFileSet()
+	// 文件按名称排序，b.go 中的方法先于 p.go
+	f, err := parser.ParseFile(fset, "/src/p.go", src, parser.ParseComments)
+	require.NoError(t, err)
+	g, err := parser.ParseFile(fset, "/src/b.go", "package p\n\n// @ext:check\nfunc (t T) first() error { return nil }\n", parser.ParseComments)
+	require.NoError(t, err)
+	res := ParseASTFiles(fset, []*ast.File{f, g})
+
+	fts, err := res.CustomFuncs("T")
+	require.NoError(t, err)
+	names := make([]string, 0, len(fts))
+	for _, ft := range fts {
+		names = append(names, ft.Name)
+	}
+	assert.Equal(t, []string{"first", "withContext", "named", "plain"}, names)
+	assert.True(t, fts[1].Context)
+	assert.Equal(t, "T", fts[1].Recv.Value)
+	assert.Equal(t, "T", fts[3].Recv.Value)
+	assert.Equal(t, "err", fts[2].Returns.Name)
+
+	f, err = parser.ParseFile(fset, "/src/u.go", bad, parser.ParseComments)
+	require.NoError(t, err)
+	res = ParseASTFiles(fset, []*ast.File{f})
+	want := []string{
+		"/src/u.go:8:22: U.wrongParam: 自定义函数签名不正确，签名应为：func() error 或 func(context.Context) error",
+		"/src/u.go:11:23: U.twoContexts: 自定义函数签名不正确，签名应为：func() error 或 func(context.Context) error",
+		"/src/u.go:14:22: U.noResult: 自定义函数签名不正确，签名应为：func() error 或 func(context.Context) error",
+		"/src/u.go:17:25: U.boolResult: 自定义函数签名不正确，签名应为：func() error 或 func(context.Context) error",
+		"/src/u.go:20:6: function: // @ext:check 只能用于结构体的方法",
+	}
+	got := make([]string, 0, len(res.FuncType))
+	for _, ft := range res.FuncType {
+		require.Error(t, ft.Err, ft.Name)
+		var d *Diagnostic
+		require.ErrorAs(t, ft.Err, &d)
+		got = append(got, d.Pos.String()+": "+d.Msg)
+	}
+	assert.Equal(t, want, got)
+	_, err = res.CustomFuncs("U")
+	assert.EqualError(t, err, "/src/u.go:8:22: U.wrongParam: 自定义函数签名不正确，签名应为：func() error 或 func(context.Context) error")
+}
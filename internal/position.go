@@ -0,0 +1,187 @@
+package internal
+
+import (
+	"SJT/struct-validate/utils"
+	"errors"
+	"fmt"
+	"go/token"
+	"path/filepath"
+)
+
+// Diagnostic is an error located in the source, printed as file:line:col: message.
+type Diagnostic struct {
+	Pos token.Position
+	Msg string
+}
+
+func (d *Diagnostic) Error() string {
+	if !d.Pos.IsValid() {
+		return d.Msg
+	}
+	pos := d.Pos
+	pos.Filename = utils.ShortPath(pos.Filename)
+	return pos.String() + ": " + d.Msg
+}
+
+// FieldTag is the tag literal of a struct field as written in the source.
+type FieldTag struct {
+	Pos token.Position // Pos 标签字面量的位置
+	Lit string         // Lit 标签字面量，包含引号
+}
+
+// RulePos returns the position of rule within the tag key, or the position
+// of the tag literal when the rule cannot be located.
+func (f *FieldTag) RulePos(key string, rule *Tag) token.Position {
+	pos := f.Pos
+	if f.Lit == "" || f.Lit[0] != '`' {
+		return pos
+	}
+	i := valueOffset(f.Lit[1:len(f.Lit)-1], key)
+	if i < 0 {
+		return pos
+	}
+	// 标签值在字面量中带引号，转义字符占两个字节
+	offset := 1 + i
+	for n := 0; n < rule.offset && offset < len(f.Lit); n++ {
+		if f.Lit[offset] == '\\' {
+			offset++
+		}
+		offset++
+	}
+	pos.Column += offset
+	pos.Offset += offset
+	return pos
+}
+
+// valueOffset returns the offset of the quoted value of key in the struct tag, after
+// its opening quote, scanning the key:"value" pairs like reflect.StructTag.Lookup so
+// that a longer key ending with key does not match. It returns -1 when key is absent.
+func valueOffset(tag, key string) int {
+	offset := 0
+	for tag != "" {
+		i := 0
+		for i < len(tag) && tag[i] == ' ' {
+			i++
+		}
+		offset += i
+		tag = tag[i:]
+		if tag == "" {
+			break
+		}
+
+		i = 0
+		for i < len(tag) && tag[i] > ' ' && tag[i] != ':' && tag[i] != '"' && tag[i] != 0x7f {
+			i++
+		}
+		if i == 0 || i+1 >= len(tag) || tag[i] != ':' || tag[i+1] != '"' {
+			break
+		}
+		name := tag[:i]
+		offset += i + 1
+		tag = tag[i+1:]
+
+		i = 1
+		for i < len(tag) && tag[i] != '"' {
+			if tag[i] == '\\' {
+				i++
+			}
+			i++
+		}
+		if i >= len(tag) {
+			break
+		}
+		if name == key {
+			return offset + 1
+		}
+		offset += i + 1
+		tag = tag[i+1:]
+	}
+	return -1
+}
+
+// tagError reports a tag that cannot be applied to a field.
+type tagError struct {
+	tag *Tag
+	err error
+}
+
+func (e *tagError) Error() string {
+	return e.err.Error()
+}
+
+func (e *tagError) Unwrap() error {
+	return e.err
+}
+
+// SourceIndex locates struct fields in the source of their packages.
+// Packages are parsed on first use unless added beforehand.
+type SourceIndex struct {
+	pkgs map[string]*ParseResult
+}
+
+func NewSourceIndex() *SourceIndex {
+	return &SourceIndex{pkgs: make(map[string]*ParseResult, 2)}
+}
+
+// Add registers the parse result of the package pkgPath.
+func (s *SourceIndex) Add(pkgPath string, res *ParseResult) {
+	s.pkgs[pkgPath] = res
+}
+
+// Result returns the parse result of the package pkgPath, parsing its source on
+// first use, or nil when the source is unavailable.
+func (s *SourceIndex) Result(pkgPath string) *ParseResult {
+	return s.lookup(pkgPath)
+}
+
+// lookup returns the parse result of the package pkgPath, or nil when the source is unavailable.
+func (s *SourceIndex) lookup(pkgPath string) *ParseResult {
+	res, ok := s.pkgs[pkgPath]
+	if !ok {
+		res = s.parse(pkgPath)
+		s.pkgs[pkgPath] = res
+	}
+	return res
+}
+
+func (s *SourceIndex) parse(pkgPath string) *ParseResult {
+	relPath, _, err := getRelPathAndPkg(pkgPath)
+	if err != nil {
+		return nil
+	}
+	wd, err := utils.GetWorkDirectory()
+	if err != nil {
+		return nil
+	}
+	paths, err := utils.ScanFiles(filepath.Join(wd, relPath))
+	if err != nil {
+		return nil
+	}
+	res, err := ParseFile(paths)
+	if err != nil {
+		return nil
+	}
+	return res
+}
+
+// fieldError returns err as a Diagnostic located at field of struct typeName.
+func (s *SourceIndex) fieldError(pkgPath, typeName, field, key string, err error) error {
+	d := &Diagnostic{Msg: fmt.Sprintf("%s.%s: %s", typeName, field, err)}
+	res := s.lookup(pkgPath)
+	if res == nil {
+		return d
+	}
+	ft, ok := res.Tags[typeName+"."+field]
+	if !ok {
+		// 字段没有标签时定位到结构体
+		d.Pos = res.Positions[typeName]
+		return d
+	}
+	var te *tagError
+	if errors.As(err, &te) {
+		d.Pos = ft.RulePos(key, te.tag)
+	} else {
+		d.Pos = ft.Pos
+	}
+	return d
+}
@@ -3,7 +3,12 @@ package internal
 import (
 	"SJT/struct-validate/utils"
 	"SJT/struct-validate/utils/slice"
+	"errors"
 	"fmt"
+	"go/token"
+	"regexp"
+	"strconv"
+	"strings"
 )
 
 type Operator string
@@ -23,9 +28,9 @@ const (
 	Lte Operator = "lte"
 	// Gte gte 大于等于
 	Gte Operator = "gte"
-	// Max 字符最大长度
+	// Max 字符串、切片或map的最大长度
 	Max Operator = "max"
-	// Min 字符最小长度
+	// Min 字符串、切片或map的最小长度
 	Min Operator = "min"
 	// UUID3 uuid3
 	UUID3     Operator = "uuid3"
@@ -37,6 +42,24 @@ const (
 	Latitude  Operator = "latitude"
 	Longitude Operator = "longitude"
 	Phone     Operator = "phone"
+	// OmitEmpty omitempty 零值时跳过后续规则
+	OmitEmpty Operator = "omitempty"
+	// Required required 不能为零值
+	Required Operator = "required"
+	// NonZero nonzero 等同于 required
+	NonZero Operator = "nonzero"
+	// EqFold eqfold 忽略大小写等于
+	EqFold Operator = "eqfold"
+	// NeFold nefold 忽略大小写不等于
+	NeFold Operator = "nefold"
+	// Lexical lexical 字符串按字典序比较 lt, gt, lte, gte
+	Lexical Operator = "lexical"
+	// Regexp regexp 匹配自定义正则表达式
+	Regexp Operator = "regexp"
+	// Func func 调用包中的校验函数 func(T) error 或 func(T) bool
+	Func Operator = "func"
+	// Dive dive 验证切片或数组的每个元素
+	Dive Operator = "dive"
 )
 
 func (s Operator) String() string {
@@ -63,6 +86,15 @@ var roles = map[Operator]struct{}{
 	Max:       {},
 	Min:       {},
 	Phone:     {},
+	OmitEmpty: {},
+	Required:  {},
+	NonZero:   {},
+	EqFold:    {},
+	NeFold:    {},
+	Lexical:   {},
+	Regexp:    {},
+	Func:      {},
+	Dive:      {},
 }
 
 var normalRoles = map[Operator]string{
@@ -75,6 +107,8 @@ var normalRoles = map[Operator]string{
 	Gte:      "<",
 	Max:      "",
 	Min:      "",
+	EqFold:   "",
+	NeFold:   "",
 }
 
 var regexpRoles = map[Operator]string{
@@ -89,6 +123,16 @@ var regexpRoles = map[Operator]string{
 	Phone:     `^1[3456789]\d{9}$`,
 }
 
+// RegexpOperator returns the operator whose built-in regular expression is pattern.
+func RegexpOperator(pattern string) (Operator, bool) {
+	for op, p := range regexpRoles {
+		if p == pattern {
+			return op, true
+		}
+	}
+	return "", false
+}
+
 func (t Tag) Check(operator string) bool {
 	_, ok := roles[Operator(operator)]
 	return ok
@@ -104,9 +148,12 @@ func (t Tag) GeError(field, operator string, value any) string {
 		return fmt.Sprintf("%s必须 %s %v", field, Operator(operator).String(), value)
 	}
 
-	if _, ok := regexpRoles[Operator(operator)]; ok {
+	if _, ok := regexpRoles[Operator(operator)]; ok || Operator(operator) == Regexp {
 		return fmt.Sprintf("%s 的规则不匹配", field)
 	}
+	if Operator(operator) == Func {
+		return fmt.Sprintf("%s 未通过 %v 校验", field, value)
+	}
 	return ""
 }
 
@@ -115,25 +162,51 @@ type Expression interface {
 	get(field, star, operator string, value any, realType string) string
 }
 
-func (*Tag) GetExp(field, star, operator string, value any, realType string) string {
+func (t *Tag) GetExp(field, star, operator string, value any, realType string) string {
+	return t.exp(DefaultReceiver+"."+field, star, operator, value, realType)
+}
+
+// exp returns the expression that holds when the field selected by ref violates the rule.
+func (*Tag) exp(ref, star, operator string, value any, realType string) string {
 	if ot, ok := normalRoles[Operator(operator)]; ok {
 		switch operator {
 		case NotEmpty.String():
 			if realType == "string" {
-				return fmt.Sprintf(`%st.%s %s ""`, star, field, ot)
+				return fmt.Sprintf(`%s%s %s ""`, star, ref, ot)
 			}
 		case Max.String():
-			if realType == "string" {
-				return fmt.Sprintf(`len(%st.%s) >= %s`, star, field, value)
+			if hasLen(realType) {
+				return fmt.Sprintf(`len(%s%s) >= %s`, star, ref, value)
 			}
 		case Min.String():
+			if hasLen(realType) {
+				return fmt.Sprintf(`len(%s%s) < %s`, star, ref, value)
+			}
+		case EqFold.String(), NeFold.String():
 			if realType == "string" {
-				return fmt.Sprintf(`len(%st.%s) < %s`, star, field, value)
+				not := "!"
+				if operator == NeFold.String() {
+					not = ""
+				}
+				return fmt.Sprintf("%sstrings.EqualFold(%s%s, %s)", not, star, ref, stringLiteral(value))
 			}
 		default:
+			if realType == "string" && (operator == Eq.String() || operator == Ne.String()) {
+				return fmt.Sprintf("%s%s %s %s", star, ref, ot, stringLiteral(value))
+			}
+			if realType == "bool" && (operator == Eq.String() || operator == Ne.String()) {
+				// eq true 与 ne false 要求为 true
+				if (operator == Eq.String()) == (fmt.Sprint(value) == "true") {
+					return fmt.Sprintf("!%s%s", star, ref)
+				}
+				return fmt.Sprintf("%s%s", star, ref)
+			}
 			// 数字类型
+			if slice.Contains[string](complexes, realType) && operator != Eq.String() && operator != Ne.String() {
+				return ""
+			}
 			if slice.Contains[string](numeric, realType) {
-				return fmt.Sprintf("%st.%s %s %s", star, field, ot, value)
+				return fmt.Sprintf("%s%s %s %s", star, ref, ot, value)
 			}
 		}
 
@@ -141,12 +214,54 @@ func (*Tag) GetExp(field, star, operator string, value any, realType string) str
 
 	if _regexp, ok := regexpRoles[Operator(operator)]; ok {
 		if realType == "string" {
-			return fmt.Sprintf("!regexp.MustCompile(`%s`).MatchString(t.%s)", _regexp, field)
+			return fmt.Sprintf("!regexp.MustCompile(`%s`).MatchString(%s%s)", _regexp, star, ref)
 		}
 	}
+	if operator == Regexp.String() && realType == "string" {
+		return fmt.Sprintf("!regexp.MustCompile(%s).MatchString(%s%s)", stringLiteral(value), star, ref)
+	}
 	return ""
 }
 
+// Exp returns the expression that holds when the field violates tag.
+func (n *Node) Exp(tag *Tag) string {
+	if Operator(tag.Operator) == Func {
+		if tag.fn == nil || tag.fn.Result != "bool" {
+			return ""
+		}
+		if guard := n.FuncGuard(tag); guard != "" {
+			return guard + " && !" + n.FuncCall(tag)
+		}
+		return "!" + n.FuncCall(tag)
+	}
+	if n.RealType == "string" && n.HasTag(Lexical.String()) {
+		switch Operator(tag.Operator) {
+		case Lt, Gt, Lte, Gte:
+			return fmt.Sprintf("%s%s %s %s", n.GetStarType(), n.Ref(), normalRoles[Operator(tag.Operator)], stringLiteral(tag.Value))
+		}
+	}
+	return tag.exp(n.Ref(), n.GetStarType(), tag.Operator, tag.Value, n.RealType)
+}
+
+// hasLen reports whether the length of the kind realType is limited by max and min.
+func hasLen(realType string) bool {
+	return realType == "string" || realType == "slice" || realType == "map"
+}
+
+// stringLiteral returns value as a quoted Go string literal; value may already be quoted.
+func stringLiteral(value any) string {
+	return strconv.Quote(stringValue(value))
+}
+
+// stringValue returns value as a string, unquoting it when it is quoted.
+func stringValue(value any) string {
+	literal := fmt.Sprint(value)
+	if unquoted, err := strconv.Unquote(literal); err == nil {
+		return unquoted
+	}
+	return literal
+}
+
 // GetStarType returns * when field is pointer
 func (n *Node) GetStarType() string {
 	if n.Kind == "ptr" {
@@ -174,5 +289,373 @@ func (n *Node) ShouldValidateNil() bool {
 	return false
 }
 
+// NotZeroExp returns the expression that holds when the field is not its zero value,
+// or "" when the field's kind has no such expression.
+func (n *Node) NotZeroExp() string {
+	if n.Kind == "ptr" {
+		return fmt.Sprintf("%s != nil", n.Ref())
+	}
+	switch {
+	case n.RealType == "string":
+		return fmt.Sprintf(`%s != ""`, n.Ref())
+	case n.RealType == "bool":
+		return n.Ref()
+	case n.RealType == "slice" || n.RealType == "map":
+		return fmt.Sprintf("len(%s) != 0", n.Ref())
+	case n.RealType == "chan" || n.RealType == "func" || n.RealType == "interface":
+		return fmt.Sprintf("%s != nil", n.Ref())
+	case slice.Contains[string](numeric, n.RealType):
+		return fmt.Sprintf("%s != 0", n.Ref())
+	case n.RealType == "struct" && n.Comparable && n.TypeName != "":
+		return fmt.Sprintf("%s != (%s{})", n.Ref(), n.TypeName)
+	}
+	return ""
+}
+
+// ZeroExp returns the expression that holds when the field is its zero value,
+// or "" when the field's kind has no such expression.
+func (n *Node) ZeroExp() string {
+	if n.Kind == "ptr" {
+		return fmt.Sprintf("%s == nil", n.Ref())
+	}
+	switch {
+	case n.RealType == "string":
+		return fmt.Sprintf(`%s == ""`, n.Ref())
+	case n.RealType == "bool":
+		return "!" + n.Ref()
+	case n.NilAble():
+		return fmt.Sprintf("%s == nil", n.Ref())
+	case slice.Contains[string](numeric, n.RealType):
+		return fmt.Sprintf("%s == 0", n.Ref())
+	case n.RealType == "struct" && n.Comparable && n.TypeName != "":
+		return fmt.Sprintf("%s == (%s{})", n.Ref(), n.TypeName)
+	}
+	return ""
+}
+
+// NilAble reports whether the zero value of the field is nil.
+func (n *Node) NilAble() bool {
+	if n.Kind == "ptr" {
+		return true
+	}
+	switch n.RealType {
+	case "slice", "map", "chan", "func", "interface":
+		return true
+	}
+	return false
+}
+
+// IsRequired reports whether the field is tagged with required or nonzero.
+func (n *Node) IsRequired() bool {
+	return n.HasTag(Required.String()) || n.HasTag(NonZero.String())
+}
+
+// ValidatesNested reports whether the nested struct of the field is always validated:
+// the field is required, or a struct value that is recursive, embedded or an element
+// of a dive rule.
+func (n *Node) ValidatesNested() bool {
+	return n.IsRequired() || ((n.Recursive || n.Embedded || n.dive) && n.Kind != "ptr")
+}
+
+// ValidatesNestedNotNil reports whether the nested struct of the field is validated
+// when the pointer is not nil: the field is a recursive or embedded pointer, or a
+// pointer element of a dive rule.
+func (n *Node) ValidatesNestedNotNil() bool {
+	return !n.ValidatesNested() && n.Kind == "ptr" && (n.Recursive || n.Embedded || n.dive)
+}
+
+// RequiredError returns the error message for a field holding its zero value.
+func (n *Node) RequiredError() string {
+	if n.NilAble() {
+		return n.Field + " 不能为nil "
+	}
+	return utils.UnderscoreName(n.Field) + "不能为空"
+}
+
+// checkTags reports the tags that cannot be applied to the field.
+func checkTags(n *Node) error {
+	for _, tag := range n.Tags {
+		if err := checkTag(n, tag); err != nil {
+			return &tagError{tag: tag, err: err}
+		}
+	}
+	return nil
+}
+
+// checkTag reports whether tag can be applied to the field.
+func checkTag(n *Node, tag *Tag) error {
+	if !tag.Check(tag.Operator) {
+		return fmt.Errorf("unknown operator %q", tag.Operator)
+	}
+	if want, got := arity(Operator(tag.Operator)), argCount(tag); want != got {
+		return fmt.Errorf("%s takes %d argument(s) but %d given", tag.Operator, want, got)
+	}
+	// 修饰符重复时生成的代码括号不成对
+	if _, ok := modifiers[Operator(tag.Operator)]; ok && repeated(n, tag) {
+		return fmt.Errorf("duplicate %s modifier", tag.Operator)
+	}
+	switch Operator(tag.Operator) {
+	case Eq, Ne, Lt, Gt, Lte, Gte:
+		var err error
+		switch {
+		case slice.Contains[string](numeric, n.RealType):
+			err = checkNumber(n.RealType, tag.Operator, tag.Value)
+		case n.RealType == "string" || n.RealType == "bool":
+			err = checkComparison(n, tag)
+		}
+		if err != nil {
+			return fmt.Errorf("%s %v: %w", tag.Operator, tag.Value, err)
+		}
+	case EqFold, NeFold:
+		if n.RealType != "string" {
+			return fmt.Errorf("%s is not supported on %s", tag.Operator, n.RealType)
+		}
+		if tag.Value == nil {
+			return fmt.Errorf("%s: missing value", tag.Operator)
+		}
+	case Lexical:
+		if n.RealType != "string" {
+			return fmt.Errorf("%s is not supported on %s", Lexical, n.RealType)
+		}
+	case OmitEmpty:
+		if n.NotZeroExp() == "" {
+			return fmt.Errorf("%s is not supported on %s", OmitEmpty, n.RealType)
+		}
+	case Required, NonZero:
+		if n.ZeroExp() == "" && !n.HasValidator() {
+			return fmt.Errorf("%s is not supported on %s", tag.Operator, n.RealType)
+		}
+	case Max, Min:
+		if err := checkNumber("int", tag.Operator, tag.Value); err != nil {
+			return fmt.Errorf("%s %v: %w", tag.Operator, tag.Value, err)
+		}
+		if length, _ := strconv.ParseInt(fmt.Sprint(tag.Value), 0, 64); length < 0 {
+			return fmt.Errorf("%s %v: negative length", tag.Operator, tag.Value)
+		}
+	case Regexp:
+		if _, err := regexp.Compile(stringValue(tag.Value)); err != nil {
+			return fmt.Errorf("invalid regexp: %w", err)
+		}
+	case Func:
+		if !token.IsIdentifier(fmt.Sprint(tag.Value)) {
+			return fmt.Errorf("invalid function name %q", tag.Value)
+		}
+	case Dive:
+		if n.Kind != "slice" && n.Kind != "array" {
+			return fmt.Errorf("%s is not supported on %s", Dive, n.Kind)
+		}
+		if !n.Elem.validated() {
+			return fmt.Errorf("%s is not supported on %s: the elements have no validator", Dive, n.GoType)
+		}
+	}
+	// 其余规则生成表达式，不能生成时说明字段类型不匹配
+	_, normal := normalRoles[Operator(tag.Operator)]
+	_, re := regexpRoles[Operator(tag.Operator)]
+	if (normal || re || Operator(tag.Operator) == Regexp) && n.Exp(tag) == "" {
+		return fmt.Errorf("%s is not supported on %s", tag.Operator, n.RealType)
+	}
+	return nil
+}
+
+// modifiers 修饰字段的其他规则，每个字段只能出现一次
+var modifiers = map[Operator]struct{}{
+	OmitEmpty: {},
+	Lexical:   {},
+}
+
+// repeated reports whether the operator of tag appears earlier in the tags of the field.
+func repeated(n *Node, tag *Tag) bool {
+	for _, t := range n.Tags {
+		if t == tag {
+			return false
+		}
+		if t.Operator == tag.Operator {
+			return true
+		}
+	}
+	return false
+}
+
+// arity returns the number of arguments the operator takes.
+func arity(op Operator) int {
+	if _, ok := normalRoles[op]; ok && op != NotEmpty {
+		return 1
+	}
+	if op == Regexp || op == Func {
+		return 1
+	}
+	return 0
+}
+
+// argCount returns the number of arguments of tag; a quoted string and a regexp pattern are one argument.
+func argCount(tag *Tag) int {
+	if tag.Value == nil {
+		return 0
+	}
+	literal := fmt.Sprint(tag.Value)
+	if _, err := strconv.Unquote(literal); err == nil || Operator(tag.Operator) == Regexp {
+		return 1
+	}
+	return len(strings.Fields(literal))
+}
+
+// HasTag reports whether the field's tag contains operator.
+func (n *Node) HasTag(operator string) bool {
+	for _, tag := range n.Tags {
+		if tag.Operator == operator {
+			return true
+		}
+	}
+	return false
+}
+
+// FieldPath returns the name used to address the field in ValidateFields paths.
+func (n *Node) FieldPath() string {
+	return utils.UnderscoreName(n.Field)
+}
+
+// HasValidator reports whether a validator is generated for the field's struct type.
+func (n *Node) HasValidator() bool {
+	return n.hasNested() && n.Package != "" && n.helper == ""
+}
+
+// hasNested reports whether the field is a named struct whose fields are validated.
+func (n *Node) hasNested() bool {
+	return n.RealType == "struct" && (n.Fields != nil || n.Recursive || n.Generic) && n.EntityName != ""
+}
+
+// IsInterface reports whether the field is an interface, or a type parameter whose
+// constraint has no validate method, whose dynamic value is validated when it
+// implements ValidateContext or the validate method.
+func (n *Node) IsInterface() bool {
+	return n.Kind == "interface" || (n.Kind == TypeParam && n.ConstraintMethod() == "")
+}
+
+// AnyRef returns the operand of the type switch on the dynamic value of the field.
+func (n *Node) AnyRef() string {
+	if n.Kind == TypeParam {
+		return "any(" + n.Ref() + ")"
+	}
+	return n.Ref()
+}
+
+// ConstraintMethod returns the validate method of the field of a type parameter
+// that its constraint requires, preferring ValidateContext, or "" when there is none.
+func (n *Node) ConstraintMethod() string {
+	if n.Kind != TypeParam {
+		return ""
+	}
+	for _, name := range []string{ContextMethod, n.MethodName()} {
+		if slice.Contains[string](n.constraint, name) {
+			return name
+		}
+	}
+	return ""
+}
+
+// ChecksNil reports whether the field, or the element of its dive rule, is an interface
+// or a type parameter whose value is checked for nil and nil pointers at run time.
+func (n *Node) ChecksNil() bool {
+	return n.IsInterface() || n.ConstraintMethod() != "" || (n.Elem != nil && n.Elem.ChecksNil())
+}
+
+// UsesContext reports whether the code validating the nested value of the field,
+// or the elements of its dive rule, refers to ctx.
+func (n *Node) UsesContext() bool {
+	switch {
+	case (n.HasValidator() || n.Helper() != "") && (n.ValidatesNested() || n.ValidatesNestedNotNil()):
+		return true
+	case n.IsInterface() || n.ConstraintMethod() == ContextMethod:
+		return true
+	}
+	return n.Elem != nil && n.Elem.UsesContext()
+}
+
+// validated reports whether code validating the element of a dive rule can be generated.
+func (n *Node) validated() bool {
+	if n == nil {
+		return false
+	}
+	return n.hasNested() || n.Kind == "interface" || n.Kind == TypeParam
+}
+
+// checkComparison reports whether the comparison tag can be applied to a string or bool field.
+func checkComparison(n *Node, tag *Tag) error {
+	if tag.Value == nil {
+		return errors.New("missing value")
+	}
+	if n.RealType == "bool" {
+		if Operator(tag.Operator) != Eq && Operator(tag.Operator) != Ne {
+			return errors.New("bool is not ordered")
+		}
+		if v := fmt.Sprint(tag.Value); v != "true" && v != "false" {
+			return fmt.Errorf("invalid bool constant %s", v)
+		}
+		return nil
+	}
+	switch Operator(tag.Operator) {
+	case Lt, Gt, Lte, Gte:
+		if !n.HasTag(Lexical.String()) {
+			return fmt.Errorf("ordering strings requires the %s modifier", Lexical)
+		}
+	}
+	return nil
+}
+
 // numeric 数字类型
-var numeric = []string{"int", "uint", "int8", "uint8", "int32", "uint32", "int64", "uint64", "float32", "float64"}
+var numeric = []string{
+	"int", "int8", "int16", "int32", "int64",
+	"uint", "uint8", "uint16", "uint32", "uint64", "uintptr",
+	"float32", "float64", "complex64", "complex128",
+}
+
+// complexes 复数类型，只支持 eq 和 ne
+var complexes = []string{"complex64", "complex128"}
+
+// bitSizes 数字类型的位数
+var bitSizes = map[string]int{
+	"int": strconv.IntSize, "int8": 8, "int16": 16, "int32": 32, "int64": 64,
+	"uint": strconv.IntSize, "uint8": 8, "uint16": 16, "uint32": 32, "uint64": 64, "uintptr": strconv.IntSize,
+	"float32": 32, "float64": 64, "complex64": 64, "complex128": 128,
+}
+
+// checkNumber reports whether value is a literal that fits the numeric kind.
+func checkNumber(kind, operator string, value any) error {
+	if value == nil {
+		return errors.New("missing value")
+	}
+	literal := fmt.Sprint(value)
+	// strconv 接受 Inf 和 NaN，但它们不是 Go 的数字字面量，生成的代码无法编译
+	if lower := strings.ToLower(literal); strings.Contains(lower, "inf") || strings.Contains(lower, "nan") {
+		return fmt.Errorf("invalid %s constant %s", kind, literal)
+	}
+	bitSize := bitSizes[kind]
+	var err error
+	switch {
+	case slice.Contains[string](complexes, kind):
+		if operator != Eq.String() && operator != Ne.String() {
+			return fmt.Errorf("%s is not ordered", kind)
+		}
+		_, err = strconv.ParseComplex(literal, bitSize)
+	case strings.HasPrefix(kind, "float"):
+		_, err = strconv.ParseFloat(literal, bitSize)
+	case strings.HasPrefix(kind, "uint"):
+		_, err = strconv.ParseUint(literal, 0, bitSize)
+	default:
+		_, err = strconv.ParseInt(literal, 0, bitSize)
+	}
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, strconv.ErrRange) {
+		return fmt.Errorf("constant %s overflows %s", literal, kind)
+	}
+	// 负数赋值给无符号类型
+	if _, ierr := strconv.ParseInt(literal, 0, 64); ierr == nil || errors.Is(ierr, strconv.ErrRange) {
+		return fmt.Errorf("constant %s overflows %s", literal, kind)
+	}
+	if _, ferr := strconv.ParseFloat(literal, 64); ferr == nil {
+		return fmt.Errorf("float constant %s cannot be used with %s", literal, kind)
+	}
+	return fmt.Errorf("invalid %s constant %s", kind, literal)
+}
@@ -2,9 +2,87 @@ package internal
 
 import (
 	"testing"
+
+	"github.com/stretchr/testify/assert"
 )
 
 func TestRules(t *testing.T) {
 	//tag := &Tag{}
 	//fmt.Println(tag.Get("notEmpty", ""))
 }
+
+func TestCheckNumber(t *testing.T) {
+	tests := []struct {
+		name     string
+		kind     string
+		operator string
+		value    any
+		wantErr  string
+	}{
+		{name: "int", kind: "int", operator: "gt", value: "-1"},
+		{name: "int16", kind: "int16", operator: "lt", value: "32767"},
+		{name: "uint16", kind: "uint16", operator: "lte", value: "0xffff"},
+		{name: "uintptr", kind: "uintptr", operator: "ne", value: "0"},
+		{name: "float32", kind: "float32", operator: "gt", value: "0.00"},
+		{name: "complex128", kind: "complex128", operator: "eq", value: "1+2i"},
+		{name: "negative uint8", kind: "uint8", operator: "gt", value: "-1", wantErr: "constant -1 overflows uint8"},
+		{name: "int8 overflow", kind: "int8", operator: "lt", value: "300", wantErr: "constant 300 overflows int8"},
+		{name: "uint16 overflow", kind: "uint16", operator: "lt", value: "65536", wantErr: "constant 65536 overflows uint16"},
+		{name: "float on int", kind: "int", operator: "gt", value: "1.5", wantErr: "float constant 1.5 cannot be used with int"},
+		{name: "float on uint", kind: "uint", operator: "gt", value: "-0.5", wantErr: "float constant -0.5 cannot be used with uint"},
+		{name: "float32 overflow", kind: "float32", operator: "lt", value: "1e39", wantErr: "constant 1e39 overflows float32"},
+		{name: "invalid", kind: "int", operator: "eq", value: "abc", wantErr: "invalid int constant abc"},
+		{name: "missing", kind: "int", operator: "eq", wantErr: "missing value"},
+		{name: "float inf", kind: "float64", operator: "gt", value: "Inf", wantErr: "invalid float64 constant Inf"},
+		{name: "float signed inf", kind: "float32", operator: "lt", value: "+Inf", wantErr: "invalid float32 constant +Inf"},
+		{name: "float nan", kind: "float64", operator: "eq", value: "nan", wantErr: "invalid float64 constant nan"},
+		{name: "float NaN", kind: "float64", operator: "ne", value: "NaN", wantErr: "invalid float64 constant NaN"},
+		{name: "complex inf", kind: "complex128", operator: "eq", value: "1+Infi", wantErr: "invalid complex128 constant 1+Infi"},
+		{name: "int inf", kind: "int", operator: "gt", value: "-inf", wantErr: "invalid int constant -inf"},
+		{name: "complex ordered", kind: "complex64", operator: "lt", value: "1", wantErr: "complex64 is not ordered"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkNumber(tt.kind, tt.operator, tt.value)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestCheckTag(t *testing.T) {
+	tests := []struct {
+		name     string
+		realType string
+		tag      *Tag
+		wantErr  string
+	}{
+		{name: "regexp", realType: "string", tag: &Tag{Operator: "regexp", Value: "^[a-z]+ [0-9]$"}},
+		{name: "quoted", realType: "string", tag: &Tag{Operator: "eq", Value: `"hello world"`}},
+		{name: "unknown", realType: "string", tag: &Tag{Operator: "admin"}, wantErr: `unknown operator "admin"`},
+		{name: "extra argument", realType: "string", tag: &Tag{Operator: "notEmpty", Value: "1"}, wantErr: "notEmpty takes 0 argument(s) but 1 given"},
+		{name: "missing argument", realType: "int", tag: &Tag{Operator: "gt"}, wantErr: "gt takes 1 argument(s) but 0 given"},
+		{name: "two arguments", realType: "int", tag: &Tag{Operator: "gt", Value: "1 2"}, wantErr: "gt takes 1 argument(s) but 2 given"},
+		{name: "type mismatch", realType: "slice", tag: &Tag{Operator: "email"}, wantErr: "email is not supported on slice"},
+		{name: "invalid regexp", realType: "string", tag: &Tag{Operator: "regexp", Value: "[a-z"}, wantErr: "invalid regexp"},
+		{name: "max", realType: "string", tag: &Tag{Operator: "max", Value: "ten"}, wantErr: "invalid int constant ten"},
+		{name: "max slice", realType: "slice", tag: &Tag{Operator: "max", Value: "10"}},
+		{name: "min map", realType: "map", tag: &Tag{Operator: "min", Value: "1"}},
+		{name: "negative max", realType: "string", tag: &Tag{Operator: "max", Value: "-1"}, wantErr: "max -1: negative length"},
+		{name: "negative min", realType: "slice", tag: &Tag{Operator: "min", Value: "-0x1"}, wantErr: "min -0x1: negative length"},
+		{name: "dive", realType: "string", tag: &Tag{Operator: "dive"}, wantErr: "dive is not supported on string"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkTag(&Node{Field: "F", Kind: tt.realType, RealType: tt.realType, Tags: []*Tag{tt.tag}}, tt.tag)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
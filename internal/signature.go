@@ -0,0 +1,104 @@
+package internal
+
+import (
+	"fmt"
+	"go/token"
+)
+
+const (
+	DefaultMethod   = "Validator"
+	DefaultReceiver = "t"
+	ContextMethod   = "ValidateContext"
+)
+
+// reservedNames 生成的代码中使用的包名和局部变量，不能用作接收者名称
+var reservedNames = map[string]struct{}{
+	"errors":  {},
+	"strings": {},
+	"regexp":  {},
+	"path":    {},
+	"paths":   {},
+	"name":    {},
+	"sub":     {},
+	"err":     {},
+	"i":       {},
+	"ctx":     {},
+	"context": {},
+}
+
+// Signature describes the generated validate method: func (t *X) Validator() error by default.
+// The method calls ContextMethod, which is not configurable, with context.Background().
+type Signature struct {
+	Method   string // Method 验证方法名，为空时为 DefaultMethod
+	Receiver string // Receiver 接收者名称，为空时为 DefaultReceiver
+	Value    bool   // Value 使用值接收者，使不可寻址的值也实现验证接口
+}
+
+// MethodName returns the name of the validate method.
+func (s Signature) MethodName() string {
+	if s.Method == "" {
+		return DefaultMethod
+	}
+	return s.Method
+}
+
+// ReceiverName returns the name of the receiver.
+func (s Signature) ReceiverName() string {
+	if s.Receiver == "" {
+		return DefaultReceiver
+	}
+	return s.Receiver
+}
+
+// ReceiverType returns the receiver type of the methods generated for the struct name.
+func (s Signature) ReceiverType(name string) string {
+	if s.Value {
+		return name
+	}
+	return "*" + name
+}
+
+// Check reports whether the method and receiver names can be used in the generated code.
+func (s Signature) Check() error {
+	method := s.MethodName()
+	if !token.IsIdentifier(method) {
+		return fmt.Errorf("invalid method name %q", method)
+	}
+	if method == "ValidateFields" || method == ContextMethod {
+		return fmt.Errorf("method name %s is used by the generated code", method)
+	}
+	receiver := s.ReceiverName()
+	if !token.IsIdentifier(receiver) || receiver == "_" {
+		return fmt.Errorf("invalid receiver name %q", receiver)
+	}
+	if _, ok := reservedNames[receiver]; ok {
+		return fmt.Errorf("receiver name %s is used by the generated code", receiver)
+	}
+	return nil
+}
+
+// SetSignature sets the signature of the generated methods of e and of its fields.
+func (e *Entity) SetSignature(s Signature) {
+	e.Signature = s
+	setSignature(e.Fields, s)
+}
+
+func setSignature(nodes []*Node, s Signature) {
+	for _, n := range nodes {
+		n.signature = s
+		setSignature(n.Fields, s)
+		if n.Elem != nil {
+			setSignature([]*Node{n.Elem}, s)
+		}
+	}
+}
+
+// Ref returns the selector of the field on the receiver, such as t.Name.
+func (n *Node) Ref() string {
+	return n.signature.ReceiverName() + "." + n.Field
+}
+
+// MethodName returns the name of the validate method called on the field.
+func (n *Node) MethodName() string {
+	return n.signature.MethodName()
+}
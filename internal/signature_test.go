@@ -0,0 +1,33 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignatureCheck(t *testing.T) {
+	tests := []struct {
+		name      string
+		signature Signature
+		wantErr   string
+	}{
+		{name: "default"},
+		{name: "custom", signature: Signature{Method: "Validate", Receiver: "o", Value: true}},
+		{name: "invalid method", signature: Signature{Method: "Validate()"}, wantErr: `invalid method name "Validate()"`},
+		{name: "reserved method", signature: Signature{Method: "ValidateFields"}, wantErr: "method name ValidateFields is used by the generated code"},
+		{name: "keyword receiver", signature: Signature{Receiver: "func"}, wantErr: `invalid receiver name "func"`},
+		{name: "blank receiver", signature: Signature{Receiver: "_"}, wantErr: `invalid receiver name "_"`},
+		{name: "reserved receiver", signature: Signature{Receiver: "errors"}, wantErr: "receiver name errors is used by the generated code"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.signature.Check()
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
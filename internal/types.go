@@ -0,0 +1,303 @@
+package internal
+
+import (
+	"errors"
+	"go/types"
+	"reflect"
+)
+
+// basicKinds maps the kinds of go/types basic types to reflect kind names.
+var basicKinds = map[types.BasicKind]reflect.Kind{
+	types.Bool:          reflect.Bool,
+	types.Int:           reflect.Int,
+	types.Int8:          reflect.Int8,
+	types.Int16:         reflect.Int16,
+	types.Int32:         reflect.Int32,
+	types.Int64:         reflect.Int64,
+	types.Uint:          reflect.Uint,
+	types.Uint8:         reflect.Uint8,
+	types.Uint16:        reflect.Uint16,
+	types.Uint32:        reflect.Uint32,
+	types.Uint64:        reflect.Uint64,
+	types.Uintptr:       reflect.Uintptr,
+	types.Float32:       reflect.Float32,
+	types.Float64:       reflect.Float64,
+	types.Complex64:     reflect.Complex64,
+	types.Complex128:    reflect.Complex128,
+	types.String:        reflect.String,
+	types.UnsafePointer: reflect.UnsafePointer,
+}
+
+// TypeParam is the kind of a type parameter, which has no reflect kind.
+const TypeParam = "typeparam"
+
+// kindOf returns the reflect kind name of typ.
+func kindOf(typ types.Type) string {
+	if _, ok := typ.(*types.TypeParam); ok {
+		return TypeParam
+	}
+	switch t := typ.Underlying().(type) {
+	case *types.Basic:
+		if kind, ok := basicKinds[t.Kind()]; ok {
+			return kind.String()
+		}
+	case *types.Pointer:
+		return reflect.Ptr.String()
+	case *types.Slice:
+		return reflect.Slice.String()
+	case *types.Array:
+		return reflect.Array.String()
+	case *types.Map:
+		return reflect.Map.String()
+	case *types.Chan:
+		return reflect.Chan.String()
+	case *types.Struct:
+		return reflect.Struct.String()
+	case *types.Signature:
+		return reflect.Func.String()
+	case *types.Interface:
+		return reflect.Interface.String()
+	}
+	return reflect.Invalid.String()
+}
+
+// nameAndPkg returns the name and the package path of typ the way reflect reports them.
+func nameAndPkg(typ types.Type) (string, string) {
+	switch t := typ.(type) {
+	case *types.Named:
+		if t.Obj().Pkg() == nil {
+			return t.Obj().Name(), ""
+		}
+		return t.Obj().Name(), t.Obj().Pkg().Path()
+	case *types.Basic:
+		// byte 与 rune 是 uint8 与 int32 的别名
+		if kind, ok := basicKinds[t.Kind()]; ok {
+			return kind.String(), ""
+		}
+		return t.Name(), ""
+	}
+	return "", ""
+}
+
+// ParseTypes parses the struct type named like Parser does with reflection, for
+// callers that only have type information such as analyzers. Field errors are passed
+// to report and the field is skipped; sources locates the fields in the source.
+func (e *Entity) ParseTypes(named *types.Named, sources *SourceIndex, report func(error)) error {
+	st, ok := named.Underlying().(*types.Struct)
+	if !ok {
+		return errors.New("invalid entity")
+	}
+	name, pkgPath := nameAndPkg(named)
+	e.EntityName = name
+	relPath, _, _ := getRelPathAndPkg(pkgPath)
+	e.PkgRelPath = relPath
+	if named.Obj().Pkg() != nil {
+		e.PackageName = named.Obj().Pkg().Name()
+	}
+	e.TypeParams = nil
+	for i := 0; i < named.TypeParams().Len(); i++ {
+		e.TypeParams = append(e.TypeParams, named.TypeParams().At(i).Obj().Name())
+	}
+	if sources == nil {
+		sources = NewSourceIndex()
+	}
+	return parseTypesField(&e.Fields, named, st, &parseContext{
+		tag:     e.ParseTag,
+		parents: map[any]struct{}{},
+		sources: sources,
+		report:  report,
+	})
+}
+
+// parseTypesField parses the fields of the struct st of type t into root.
+func parseTypesField(root *[]*Node, t types.Type, st *types.Struct, ctx *parseContext) error {
+	ctx.parents[t] = struct{}{}
+	defer delete(ctx.parents, t)
+	tName, tPkgPath := nameAndPkg(t)
+	for i := 0; i < st.NumFields(); i++ {
+		field := st.Field(i)
+		tag := reflect.StructTag(st.Tag(i)).Get(ctx.tag)
+		if !field.Exported() || tag == SkipTag {
+			continue
+		}
+
+		// 禁止多重指针
+		subTyp := field.Type()
+		if p, ok := subTyp.Underlying().(*types.Pointer); ok {
+			if _, ok := p.Elem().Underlying().(*types.Pointer); ok {
+				if err := ctx.fail(tPkgPath, tName, field.Name(), errors.New("只能使用一级指针")); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+
+		curNode := &Node{}
+		curNode.Field = field.Name()
+		curNode.Embedded = field.Embedded()
+		curNode.Kind = kindOf(subTyp)
+		curNode.GoType = goType(field.Type(), tPkgPath)
+		curNode.setTags(tag)
+		subTyp, err := curNode.setTypes(t, subTyp, tPkgPath, ctx)
+		if err != nil {
+			return err
+		}
+		if curNode.HasTag(Dive.String()) {
+			var elemTyp types.Type
+			switch u := subTyp.Underlying().(type) {
+			case *types.Slice:
+				elemTyp = u.Elem()
+			case *types.Array:
+				elemTyp = u.Elem()
+			}
+			if elemTyp != nil {
+				elem := &Node{Field: field.Name() + "[i]", Kind: kindOf(elemTyp), dive: true}
+				elem.GoType = goType(elemTyp, tPkgPath)
+				if _, err := elem.setTypes(t, elemTyp, tPkgPath, ctx); err != nil {
+					return err
+				}
+				curNode.Elem = elem
+			}
+		}
+		subPkgPath := curNode.PkgPath
+		if err := curNode.finish(subPkgPath, tPkgPath); err != nil {
+			if err := ctx.fail(tPkgPath, tName, field.Name(), err); err != nil {
+				return err
+			}
+			continue
+		}
+		*root = append(*root, curNode)
+	}
+	return nil
+}
+
+// goType returns typ as written in the package pkgPath.
+func goType(typ types.Type, pkgPath string) string {
+	return canonicalType(types.TypeString(typ, func(p *types.Package) string {
+		if p.Path() == pkgPath {
+			return ""
+		}
+		return p.Name()
+	}))
+}
+
+// setTypes sets the type information of n, a field or an element of the struct t in
+// the package pkgPath, from typ, and parses the fields of its struct type. It
+// returns typ without the pointer.
+func (n *Node) setTypes(t, typ types.Type, pkgPath string, ctx *parseContext) (types.Type, error) {
+	if p, ok := typ.Underlying().(*types.Pointer); ok {
+		typ = p.Elem()
+	}
+	name, typPkgPath := nameAndPkg(typ)
+	n.RealType = kindOf(typ)
+	n.TypeName = name
+	if typPkgPath != "" && typPkgPath != pkgPath {
+		if named, ok := typ.(*types.Named); ok {
+			n.TypeName = named.Obj().Pkg().Name() + "." + name
+		}
+	}
+	n.Comparable = types.Comparable(typ)
+
+	relPath, pkg, _ := getRelPathAndPkg(typPkgPath)
+
+	n.PkgRelPath = relPath
+	n.Package = pkg
+	n.PkgPath = typPkgPath
+	named, _ := typ.(*types.Named)
+	if named != nil && named.Obj().Pkg() != nil {
+		n.PkgName = named.Obj().Pkg().Name()
+		// 包名可能与目录名不同，如 main 包
+		if n.Package != "" {
+			n.Package = n.PkgName
+		}
+	}
+	n.EntityName = name
+	if tp, ok := typ.(*types.TypeParam); ok {
+		n.constraint = constraintMethods(tp)
+	}
+	if named != nil && n.RealType == "struct" {
+		n.Recursive = reachesTypes(named, t, ctx.tag, map[*types.Named]bool{})
+	}
+	if named != nil && named.TypeArgs().Len() > 0 {
+		// 泛型结构体的实例调用其验证方法，代码按泛型声明生成
+		n.TypeName = goType(typ, pkgPath)
+		n.Generic = n.RealType == "struct"
+		return typ, nil
+	}
+	if sub, ok := typ.Underlying().(*types.Struct); ok {
+		n.typ = named
+		if _, ok := ctx.parents[typ]; !ok {
+			n.Fields = make([]*Node, 0, 10)
+			if err := parseTypesField(&n.Fields, typ, sub, ctx); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return typ, nil
+}
+
+// reachesTypes reports whether the struct typ is target or leads to it through its
+// fields like reaches. Instances of a generic struct match their declaration.
+func reachesTypes(typ *types.Named, target types.Type, tag string, seen map[*types.Named]bool) bool {
+	if t, ok := target.(*types.Named); ok && typ.Origin() == t.Origin() {
+		return true
+	}
+	if seen[typ] {
+		return false
+	}
+	seen[typ] = true
+	st, ok := typ.Underlying().(*types.Struct)
+	if !ok {
+		return false
+	}
+	for i := 0; i < st.NumFields(); i++ {
+		field := st.Field(i)
+		ruleTag := reflect.StructTag(st.Tag(i)).Get(tag)
+		if !field.Exported() || ruleTag == SkipTag {
+			continue
+		}
+		next := []types.Type{field.Type()}
+		switch u := field.Type().Underlying().(type) {
+		case *types.Slice:
+			if hasDive(ruleTag) {
+				next = append(next, u.Elem())
+			}
+		case *types.Array:
+			if hasDive(ruleTag) {
+				next = append(next, u.Elem())
+			}
+		}
+		for _, ft := range next {
+			if p, ok := ft.Underlying().(*types.Pointer); ok {
+				ft = p.Elem()
+			}
+			if named, ok := ft.(*types.Named); ok && reachesTypes(named, target, tag, seen) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// constraintMethods returns the methods of the constraint of tp that can validate
+// its values: ValidateContext(context.Context) error and the methods func() error.
+func constraintMethods(tp *types.TypeParam) []string {
+	iface, ok := tp.Constraint().Underlying().(*types.Interface)
+	if !ok {
+		return nil
+	}
+	methods := make([]string, 0, 1)
+	for i := 0; i < iface.NumMethods(); i++ {
+		m := iface.Method(i)
+		sig := m.Type().(*types.Signature)
+		if sig.Results().Len() != 1 || sig.Results().At(0).Type().String() != ErrorKeyword {
+			continue
+		}
+		switch {
+		case sig.Params().Len() == 0 && m.Name() != ContextMethod,
+			sig.Params().Len() == 1 && m.Name() == ContextMethod && sig.Params().At(0).Type().String() == "context.Context":
+			methods = append(methods, m.Name())
+		}
+	}
+	return methods
+}
@@ -3,39 +3,126 @@ package main
 import (
 	"SJT/struct-validate/pkg"
 	"SJT/struct-validate/utils"
+	"errors"
 	"fmt"
 	"github.com/spf13/cobra"
+	"os"
 	"path/filepath"
+	"strconv"
 )
 
 func main() {
-	cmd := &cobra.Command{}
+	cmd := &cobra.Command{SilenceUsage: true, SilenceErrors: true}
 	cmd.AddCommand(GenerateCmd())
-	cmd.Execute()
+	cmd.AddCommand(CleanCmd())
+	if err := cmd.Execute(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
 }
 
 func GenerateCmd() *cobra.Command {
-	return &cobra.Command{
-		Use:     "validate",
-		Short:   "generate validate code for the directory",
-		Example: "struct-validate validate .",
-		Args:    cobra.ExactArgs(1),
-		Run: func(cmd *cobra.Command, args []string) {
-			if len(args) < 1 {
-				panic("参数不正确")
-			}
-			dir, _ := filepath.Abs(args[0])
-			fmt.Println(dir, ":")
-			files, err := utils.ScanFiles(args[0])
+	var check, dryRun, stdout, here, prune, combine, valueReceiver, local bool
+	var excludes, types []string
+	var method, receiver string
+	cmd := &cobra.Command{
+		Use:   "validate [packages]",
+		Short: "generate validate code for the packages",
+		Example: `struct-validate validate .
+struct-validate validate ./... --exclude internal/mock
+struct-validate validate --type User,Order .
+struct-validate validate --method Validate --value-receiver .
+//go:generate struct-validate validate --here`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				args = []string{"."}
+			}
+			if !stdout {
+				for _, arg := range args {
+					dir, err := filepath.Abs(arg)
+					if err != nil {
+						return err
+					}
+					fmt.Println(dir, ":")
+				}
+			}
+			dirs, err := utils.ListPackages(args, excludes)
+			if err != nil {
+				return err
+			}
+			if here {
+				name, err := typeAtDirective()
+				if err != nil {
+					return err
+				}
+				types = append(types, name)
+			}
+			s := pkg.ScanFile{Dirs: dirs, Check: check, DryRun: dryRun, Stdout: stdout, Types: types, Prune: prune, Combine: combine,
+				Method: method, Receiver: receiver, ValueReceiver: valueReceiver, Local: local}
+			return s.Resolver()
+		},
+	}
+	cmd.Flags().BoolVar(&check, "check", false, "compare the generated code with the files on disk and print a diff instead of writing")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "list the files that would be created without writing them")
+	cmd.Flags().BoolVar(&stdout, "stdout", false, "print the generated code instead of writing files")
+	cmd.Flags().StringSliceVar(&excludes, "exclude", nil, "skip the package directories matching the glob, relative to the current directory")
+	cmd.Flags().StringSliceVarP(&types, "type", "t", nil, "generate only the named structs")
+	cmd.Flags().BoolVar(&here, "here", false, "generate only the struct following the go:generate directive, read from GOFILE, GOLINE and GOPACKAGE")
+	cmd.Flags().BoolVar(&prune, "prune", false, "remove the generated files of the packages that are no longer generated")
+	cmd.Flags().BoolVar(&combine, "combine", false, "write the validators of each package into one "+pkg.CombinedFileName)
+	cmd.Flags().StringVar(&method, "method", "", "name of the generated validate method (default Validator)")
+	cmd.Flags().StringVar(&receiver, "receiver", "", "name of the receiver of the generated methods (default t)")
+	cmd.Flags().BoolVar(&valueReceiver, "value-receiver", false, "generate the methods on value receivers instead of pointer receivers")
+	cmd.Flags().BoolVar(&local, "local", false, "write code only into the packages of the structs, validating nested structs of other packages with helper functions")
+	return cmd
+}
+
+func CleanCmd() *cobra.Command {
+	var dryRun bool
+	var excludes []string
+	cmd := &cobra.Command{
+		Use:     "clean [packages]",
+		Short:   "remove the generated validate code of the packages",
+		Example: "struct-validate clean ./...",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				args = []string{"."}
+			}
+			dirs, err := utils.ListPackages(args, excludes)
 			if err != nil {
-				panic(err)
+				return err
 			}
-			s := pkg.ScanFile{Files: files}
-			err = s.Resolver()
+			files, err := pkg.GeneratedFiles(dirs...)
 			if err != nil {
-				//panic(err)
-				fmt.Println(err)
+				return err
+			}
+			for _, file := range files {
+				if dryRun {
+					fmt.Println("would remove file: ", file)
+					continue
+				}
+				if err := os.Remove(file); err != nil {
+					return err
+				}
+				fmt.Println("removed file: ", file)
 			}
+			return nil
 		},
 	}
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "list the files that would be removed without removing them")
+	cmd.Flags().StringSliceVar(&excludes, "exclude", nil, "skip the package directories matching the glob, relative to the current directory")
+	return cmd
+}
+
+// typeAtDirective returns the struct following the go:generate directive that runs the command.
+func typeAtDirective() (string, error) {
+	file, line := os.Getenv("GOFILE"), os.Getenv("GOLINE")
+	if file == "" || line == "" {
+		return "", errors.New("--here 只能通过 go generate 运行，需要环境变量 GOFILE 和 GOLINE")
+	}
+	n, err := strconv.Atoi(line)
+	if err != nil {
+		return "", fmt.Errorf("invalid GOLINE %q: %w", line, err)
+	}
+	return pkg.TypeAfterLine(file, n, os.Getenv("GOPACKAGE"))
 }
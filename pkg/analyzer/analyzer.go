@@ -0,0 +1,291 @@
+// Package analyzer reports invalid check tags and missing or stale generated
+// validate files, using the same rules as the generator.
+package analyzer
+
+import (
+	"SJT/struct-validate/internal"
+	"SJT/struct-validate/pkg"
+	"SJT/struct-validate/utils"
+	"bytes"
+	"errors"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+var Analyzer = &analysis.Analyzer{
+	Name: "structvalidate",
+	Doc:  "check struct validation tags and the generated _validate.go files",
+	Run:  run,
+}
+
+var (
+	parseTag      string
+	skipStale     bool
+	method        string
+	receiver      string
+	valueReceiver bool
+	local         bool
+)
+
+func init() {
+	Analyzer.Flags.StringVar(&parseTag, "tag", internal.DefaultParseTag, "struct tag holding the validation rules")
+	Analyzer.Flags.BoolVar(&skipStale, "skip-stale", false, "do not report missing or stale _validate.go files")
+	Analyzer.Flags.StringVar(&method, "method", "", "name of the generated validate method, as passed to the generator")
+	Analyzer.Flags.StringVar(&receiver, "receiver", "", "name of the receiver of the generated methods, as passed to the generator")
+	Analyzer.Flags.BoolVar(&valueReceiver, "value-receiver", false, "the generated methods have value receivers, as passed to the generator")
+	Analyzer.Flags.BoolVar(&local, "local", false, "nested structs of other packages are validated by helper functions, as passed to the generator")
+}
+
+// signature returns the signature of the generated methods set by the flags.
+func signature() internal.Signature {
+	return internal.Signature{Method: method, Receiver: receiver, Value: valueReceiver}
+}
+
+func run(pass *analysis.Pass) (any, error) {
+	if err := signature().Check(); err != nil {
+		return nil, err
+	}
+	files := make([]*ast.File, 0, len(pass.Files))
+	for _, f := range pass.Files {
+		name := pass.Fset.File(f.Pos()).Name()
+		if strings.HasSuffix(name, "_test.go") || strings.HasSuffix(name, "_validate.go") {
+			continue
+		}
+		files = append(files, f)
+	}
+	res := internal.ParseASTFiles(pass.Fset, files)
+	res.Types = pass.Pkg
+	sources := internal.NewSourceIndex()
+	sources.Add(pass.Pkg.Path(), res)
+	for _, ft := range res.FuncType {
+		if ft.Err != nil {
+			report(pass, files, token.NoPos, ft.Err)
+		}
+	}
+
+	for _, name := range res.GetEntities() {
+		obj, ok := pass.Pkg.Scope().Lookup(name).(*types.TypeName)
+		if !ok {
+			continue
+		}
+		named, ok := obj.Type().(*types.Named)
+		if !ok {
+			continue
+		}
+		e := internal.NewEntity()
+		e.SetTag(parseTag)
+		var failed bool
+		err := e.ParseTypes(named, sources, func(err error) {
+			failed = true
+			report(pass, files, obj.Pos(), err)
+		})
+		if err != nil || failed {
+			continue
+		}
+		if err := e.ResolveFuncs(res); err != nil {
+			report(pass, files, obj.Pos(), err)
+			continue
+		}
+		// 自定义函数的错误已经报告
+		if _, err := res.CustomFuncs(name); err != nil || skipStale {
+			continue
+		}
+		checkGenerated(pass, obj, e, res, sources)
+	}
+	if !skipStale {
+		checkCombined(pass, files, res)
+	}
+	return nil, nil
+}
+
+// report reports err at its source position when it lies in files. Errors located
+// in other packages are left to the passes of those packages.
+func report(pass *analysis.Pass, files []*ast.File, fallback token.Pos, err error) {
+	var diag *internal.Diagnostic
+	if !errors.As(err, &diag) || !diag.Pos.IsValid() {
+		pass.Reportf(fallback, "%s", err)
+		return
+	}
+	for _, f := range files {
+		tf := pass.Fset.File(f.Pos())
+		if tf.Name() == diag.Pos.Filename {
+			pass.Reportf(tf.Pos(diag.Pos.Offset), "%s", diag.Msg)
+			return
+		}
+	}
+}
+
+// checkGenerated reports the struct when its _validate.go file, or the file of a
+// nested struct generated with it, is missing or differs from what the generator
+// would write.
+func checkGenerated(pass *analysis.Pass, obj *types.TypeName, e *internal.Entity, res *internal.ParseResult, sources *internal.SourceIndex) {
+	// 生成器不处理模块之外的包
+	if e.PkgRelPath == "" || !e.IsUseful() {
+		return
+	}
+	wd, err := utils.GetWorkDirectory()
+	if err != nil {
+		return
+	}
+	if msg := staleFile(wd, e, res); msg != "" {
+		pass.Reportf(obj.Pos(), "%s", msg)
+	}
+
+	// 嵌套结构体与生成器一样单独解析；本包中带有验证标签的结构体单独检查
+	checked := map[string]bool{pass.Pkg.Path() + "." + e.EntityName: true}
+	for _, name := range res.GetTaggedEntities(parseTag) {
+		checked[pass.Pkg.Path()+"."+name] = true
+	}
+	var walk func(nodes []*internal.Node)
+	walk = func(nodes []*internal.Node) {
+		for _, field := range nodes {
+			for _, n := range []*internal.Node{field, field.Elem} {
+				if n == nil || n.Generic || !n.HasValidator() || checked[n.PkgPath+"."+n.EntityName] {
+					continue
+				}
+				checked[n.PkgPath+"."+n.EntityName] = true
+				sub := internal.NewEntity()
+				sub.SetTag(parseTag)
+				subRes := sources.Result(n.PkgPath)
+				if err := sub.ParseNested(n); err != nil || subRes == nil {
+					continue
+				}
+				if msg := staleFile(wd, sub, subRes); msg != "" {
+					pass.Reportf(obj.Pos(), "nested struct %s: %s", n.EntityName, msg)
+				}
+				walk(sub.Fields)
+			}
+		}
+	}
+	walk(e.Fields)
+}
+
+// staleFile returns why the _validate.go file of e is missing or stale, or "" when
+// it is up to date or the package is generated with --combine, see checkCombined.
+func staleFile(wd string, e *internal.Entity, res *internal.ParseResult) string {
+	e.SetSignature(signature())
+	e.Local = local
+	file, code, err := pkg.GenCode(wd, e, res)
+	if err != nil {
+		return err.Error()
+	}
+	// 使用 --combine 生成时，验证代码在整个包共用的文件中
+	if ok, _ := pkg.IsGenerated(filepath.Join(filepath.Dir(file), pkg.CombinedFileName)); ok {
+		return ""
+	}
+	old, err := os.ReadFile(file)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return filepath.Base(file) + " is missing, run struct-validate validate"
+	case err != nil:
+		return err.Error()
+	case !bytes.Equal(old, code):
+		return filepath.Base(file) + " is stale, run struct-validate validate"
+	}
+	return ""
+}
+
+// checkCombined reports the structs whose validators in the CombinedFileName of the
+// package are missing or differ from what the generator would write with --combine.
+// The whole package is regenerated like the generator does, so that the shared
+// regular expressions and the nested structs are the same.
+func checkCombined(pass *analysis.Pass, files []*ast.File, res *internal.ParseResult) {
+	if len(files) == 0 {
+		return
+	}
+	dir := filepath.Dir(pass.Fset.File(files[0].Pos()).Name())
+	path := filepath.Join(dir, pkg.CombinedFileName)
+	if ok, _ := pkg.IsGenerated(path); !ok {
+		return
+	}
+	old, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+	g := pkg.NewGenDefinition()
+	g.SetTag(parseTag)
+	g.SetMethod(method)
+	g.SetReceiver(receiver, valueReceiver)
+	g.SetLocal(local)
+	g.SetCombine(true)
+	names := res.GetTaggedEntities(parseTag)
+	decls := make([]any, 0, len(names))
+	for _, name := range names {
+		decls = append(decls, pkg.Decl{Dir: dir, Name: name})
+	}
+	// 结构体的错误已经报告
+	generated, err := g.Generate(decls...)
+	if err != nil || bytes.Equal(old, generated[path]) {
+		return
+	}
+
+	oldMethods, newMethods := methodSources(old), methodSources(generated[path])
+	types := make([]string, 0, len(newMethods))
+	for name := range newMethods {
+		types = append(types, name)
+	}
+	sort.Strings(types)
+	reported := false
+	for _, name := range types {
+		obj := pass.Pkg.Scope().Lookup(name)
+		src, ok := oldMethods[name]
+		if obj == nil || src == newMethods[name] {
+			continue
+		}
+		reported = true
+		if ok {
+			pass.Reportf(obj.Pos(), "%s: validators of %s are stale, run struct-validate validate --combine", pkg.CombinedFileName, name)
+		} else {
+			pass.Reportf(obj.Pos(), "%s: validators of %s are missing, run struct-validate validate --combine", pkg.CombinedFileName, name)
+		}
+	}
+	// 差异不在某个结构体的方法中，例如导入、正则表达式或者已删除的结构体
+	if !reported {
+		pass.Reportf(files[0].Name.Pos(), "%s is stale, run struct-validate validate --combine", pkg.CombinedFileName)
+	}
+}
+
+// methodSources returns the source of the methods in src, with their doc comments,
+// keyed by the name of their receiver type.
+func methodSources(src []byte) map[string]string {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, parser.ParseComments)
+	if err != nil {
+		return nil
+	}
+	methods := make(map[string]string, len(file.Decls))
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Recv == nil || len(fn.Recv.List) != 1 {
+			continue
+		}
+		typ := fn.Recv.List[0].Type
+		if star, ok := typ.(*ast.StarExpr); ok {
+			typ = star.X
+		}
+		switch t := typ.(type) {
+		case *ast.IndexExpr:
+			typ = t.X
+		case *ast.IndexListExpr:
+			typ = t.X
+		}
+		ident, ok := typ.(*ast.Ident)
+		if !ok {
+			continue
+		}
+		start := fn.Pos()
+		if fn.Doc != nil {
+			start = fn.Doc.Pos()
+		}
+		methods[ident.Name] += string(src[fset.Position(start).Offset:fset.Position(fn.End()).Offset])
+	}
+	return methods
+}
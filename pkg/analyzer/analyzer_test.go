@@ -0,0 +1,18 @@
+package analyzer
+
+import (
+	"testing"
+
+	"golang.org/x/tools/go/analysis/analysistest"
+)
+
+func TestAnalyzer(t *testing.T) {
+	// 在模块根目录中运行，test_data/b 与 main 包 test_data/cmd 的生成文件是最新的，不应有诊断
+	analysistest.Run(t, "../..", Analyzer,
+		"SJT/struct-validate/test_data/lint",
+		"SJT/struct-validate/test_data/lint/combined",
+		"SJT/struct-validate/test_data/diag",
+		"SJT/struct-validate/test_data/b/...",
+		"SJT/struct-validate/test_data/cmd/...",
+	)
+}
@@ -0,0 +1,93 @@
+package pkg
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// GeneratedHeader is the first line of every generated file. Only files starting
+// with it are removed by Clean and by pruning, hand-written files are never touched.
+const GeneratedHeader = "// Code generated by struct-validate. DO NOT EDIT."
+
+// IsGenerated reports whether the file at path was written by the generator,
+// that is whether GeneratedHeader appears before its package clause.
+func IsGenerated(path string) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == GeneratedHeader {
+			return true, nil
+		}
+		if strings.HasPrefix(line, "package ") {
+			return false, nil
+		}
+	}
+	return false, scanner.Err()
+}
+
+// generatedTypes returns the types named in the header of the generated file at path:
+// the type of a per struct file, or the types listed in a combined file. It returns
+// nil when there is no such file.
+func generatedTypes(path string) []string {
+	if ok, _ := IsGenerated(path); !ok {
+		return nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var types []string
+	listed := false
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "// type: "):
+			return []string{strings.TrimPrefix(line, "// type: ")}
+		case line == "// types:":
+			listed = true
+		case listed && strings.HasPrefix(line, "// \t"):
+			types = append(types, strings.TrimPrefix(line, "// \t"))
+		case listed:
+			return types
+		}
+	}
+	return types
+}
+
+// GeneratedFiles returns the sorted generated _validate.go files in dirs.
+func GeneratedFiles(dirs ...string) ([]string, error) {
+	files := make([]string, 0, 10)
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if entry.IsDir() || !strings.HasSuffix(entry.Name(), "_validate.go") {
+				continue
+			}
+			path := filepath.Join(dir, entry.Name())
+			ok, err := IsGenerated(path)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				files = append(files, path)
+			}
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
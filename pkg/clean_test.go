@@ -0,0 +1,48 @@
+package pkg
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGeneratedFiles(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"user_validate.go":    GeneratedHeader + "\n\npackage model\n",
+		"order_validate.go":   "// Package model\npackage model\n\n" + GeneratedHeader + "\n",
+		"manual_validate.go":  "package model\n",
+		"generated.go":        GeneratedHeader + "\n\npackage model\n",
+		"comment_validate.go": "// Copyright\n\n" + GeneratedHeader + "\npackage model\n",
+	}
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+
+	got, err := GeneratedFiles(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(dir, "comment_validate.go"),
+		filepath.Join(dir, "user_validate.go"),
+	}, got)
+}
+
+func TestGeneratedTypes(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, CombinedFileName)
+	assert.Nil(t, generatedTypes(path))
+
+	header := GeneratedHeader + "\n// versions:\n// \tstruct-validate (devel)\n// types:\n// \tAddress\n// \tStop\n\npackage b\n"
+	require.NoError(t, os.WriteFile(path, []byte(header), 0644))
+	assert.Equal(t, []string{"Address", "Stop"}, generatedTypes(path))
+
+	require.NoError(t, os.WriteFile(path, []byte("package b\n\n// types:\n// \tAddress\n"), 0644))
+	assert.Nil(t, generatedTypes(path))
+
+	header = GeneratedHeader + "\n// versions:\n// \tstruct-validate (devel)\n// source: test_data/b/address.go\n// type: Address\n\npackage b\n"
+	require.NoError(t, os.WriteFile(path, []byte(header), 0644))
+	assert.Equal(t, []string{"Address"}, generatedTypes(path))
+}
@@ -3,16 +3,21 @@ package pkg
 import (
 	"SJT/struct-validate/internal"
 	"SJT/struct-validate/utils"
+	"SJT/struct-validate/utils/slice"
 	"bytes"
 	"errors"
 	"fmt"
 	"go/format"
-	"log"
+	"go/types"
+	"io/fs"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
 	"strings"
-	"text/template"
+	"sync"
 )
 
 type Generator interface {
@@ -20,13 +25,36 @@ type Generator interface {
 }
 
 type GenDefinition struct {
-	entities []*internal.Entity
-	parseTag string
+	entities  []*internal.Entity
+	parseTag  string
+	generated map[string]struct{}           // generated 已生成验证代码的类型，避免递归类型重复生成
+	files     map[string][]byte             // files 生成的文件，键为文件的绝对路径
+	check     bool                          // check 只比较生成的代码与磁盘上的文件，不写入
+	dryRun    bool                          // dryRun 只列出将要生成的文件，不写入
+	stdout    bool                          // stdout 将生成的代码打印到标准输出，不写入
+	prune     []string                      // prune 在这些目录中删除本次没有生成的旧文件
+	combine   bool                          // combine 每个包只生成一个 CombinedFileName 文件
+	packages  map[string][]*internal.Entity // packages combine 模式下按目录分组的结构体
+	signature internal.Signature            // signature 生成的验证方法的签名
+	local     bool                          // local 不在结构体所在的包之外生成代码
+	wd        string
+	sources   map[string]*source        // sources 已解析的包目录
+	decls     map[string]*types.Package // decls 解析 Decl 时加载的包，键为目录
+	mu        sync.Mutex                // mu 保护并发生成时的 generated、files 和 sources
 }
 
 var _ Generator = &GenDefinition{}
 
-var createdFiles = map[string]struct{}{}
+// Decl names a struct declared in the package in Dir, which Gen parses from the
+// source with go/types instead of reflection. Generic structs, which reflection
+// only sees instantiated, are generated this way with their type parameters.
+type Decl struct {
+	Dir  string // Dir 包所在的目录，相对路径相对于工作目录
+	Name string // Name 结构体名称
+}
+
+// CombinedFileName is the file holding the validators of a whole package, see GenDefinition.SetCombine.
+const CombinedFileName = "zz_generated_validate.go"
 
 func NewGenDefinition() *GenDefinition {
 	return &GenDefinition{entities: make([]*internal.Entity, 0, 10)}
@@ -37,37 +65,244 @@ func (g *GenDefinition) SetTag(tag string) {
 	g.parseTag = tag
 }
 
+// SetCheck 设置检查模式：在内存中生成代码并与磁盘上的文件比较，打印差异而不写入文件，
+// 存在差异时 Gen 返回错误
+func (g *GenDefinition) SetCheck(check bool) {
+	g.check = check
+}
+
+// SetDryRun 设置试运行模式：只列出将要生成的文件，不写入
+func (g *GenDefinition) SetDryRun(dryRun bool) {
+	g.dryRun = dryRun
+}
+
+// SetStdout 设置将生成的代码打印到标准输出，不写入文件
+func (g *GenDefinition) SetStdout(stdout bool) {
+	g.stdout = stdout
+}
+
+// SetCombine 设置将同一个包中所有结构体的验证代码写入一个 CombinedFileName 文件，
+// 合并导入并共享预编译的正则表达式；默认每个结构体生成一个文件
+func (g *GenDefinition) SetCombine(combine bool) {
+	g.combine = combine
+}
+
+// SetMethod 设置验证方法名，默认为 Validator；嵌套结构体调用同名方法
+func (g *GenDefinition) SetMethod(name string) {
+	g.signature.Method = name
+}
+
+// SetReceiver 设置验证方法的接收者名称，默认为 t；value 为 true 时使用值接收者，
+// 使不可寻址的值也实现验证接口
+func (g *GenDefinition) SetReceiver(name string, value bool) {
+	g.signature.Receiver = name
+	g.signature.Value = value
+}
+
+// SetLocal 设置只在结构体所在的包中生成代码：其他包中的嵌套结构体也使用生成在本包中的
+// 辅助函数验证，而不是在其包中生成验证方法；@path 指向其他目录时报错
+func (g *GenDefinition) SetLocal(local bool) {
+	g.local = local
+}
+
+// SetPrune 设置在 dirs 中删除本次没有生成的旧文件，例如结构体改名或删除时留下的文件。
+// 结构体仍然存在的文件重新生成而不删除，因为其他包的验证代码可能调用它。只删除带有 GeneratedHeader 的文件
+func (g *GenDefinition) SetPrune(dirs ...string) {
+	g.prune = dirs
+}
+
+// Gen generates the validate code of entities and writes it according to the output mode.
 func (g *GenDefinition) Gen(entities ...any) error {
-	//fmt.Println("generating validate codes...")
+	files, err := g.Generate(entities...)
+	if err != nil {
+		return err
+	}
+	paths := make([]string, 0, len(files))
+	for path := range files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	stale, err := g.staleFiles(files)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case g.check:
+		return checkFiles(paths, files, stale)
+	case g.stdout:
+		for _, path := range paths {
+			fmt.Printf("// %s\n%s", utils.ShortPath(path), files[path])
+		}
+	case g.dryRun:
+		for _, path := range paths {
+			fmt.Println("would create file: ", path)
+		}
+		for _, path := range stale {
+			fmt.Println("would remove file: ", path)
+		}
+	default:
+		for _, path := range paths {
+			if err := writeFile(path, files[path]); err != nil {
+				return err
+			}
+			fmt.Println("created file: ", path)
+		}
+		for _, path := range stale {
+			if err := os.Remove(path); err != nil {
+				return err
+			}
+			fmt.Println("removed file: ", path)
+		}
+	}
+	return nil
+}
+
+// staleFiles returns the generated files in the prune directories that are not in files.
+func (g *GenDefinition) staleFiles(files map[string][]byte) ([]string, error) {
+	if len(g.prune) == 0 {
+		return nil, nil
+	}
+	generated, err := GeneratedFiles(g.prune...)
+	if err != nil {
+		return nil, err
+	}
+	stale := make([]string, 0, len(generated))
+	for _, path := range generated {
+		if _, ok := files[path]; !ok {
+			stale = append(stale, path)
+		}
+	}
+	return stale, nil
+}
+
+// Generate parses entities and returns the generated validate files keyed by their
+// absolute path, including the files of nested structs, without writing them.
+func (g *GenDefinition) Generate(entities ...any) (map[string][]byte, error) {
+	if err := g.signature.Check(); err != nil {
+		return nil, err
+	}
 	for _, entity := range entities {
 		e := internal.NewEntity()
 		if g.parseTag != "" {
 			e.SetTag(g.parseTag)
 		}
-		err := e.Parser(entity)
+		var err error
+		if d, ok := entity.(Decl); ok {
+			err = g.parseDecl(e, d)
+		} else {
+			err = e.Parser(entity)
+		}
+		var diag *internal.Diagnostic
+		if errors.As(err, &diag) && diag.Pos.IsValid() {
+			return nil, err
+		}
 		if err != nil {
-			return err
+			return nil, fmt.Errorf("parse %T: %w", entity, err)
 		}
 
-		// 过滤不需要做验证的实体
-		//var isIgnore = true
-		//for _, field := range e.Fields {
-		//	for _, _ = range field.Tags {
-		//		isIgnore = false
-		//	}
-		//}
-		//if !isIgnore {
-		//	g.entities = append(g.entities, e)
-		//}
 		if e.IsUseful() {
 			g.entities = append(g.entities, e)
 		}
 	}
-	g.GenValidation()
-	for f, _ := range createdFiles {
-		fmt.Println("created file: ", f)
+	if err := g.GenValidation(); err != nil {
+		return nil, err
+	}
+	return g.files, nil
+}
+
+// parseDecl parses the struct named by d into e with go/types, loading each
+// package once per generation.
+func (g *GenDefinition) parseDecl(e *internal.Entity, d Decl) error {
+	dir := d.Dir
+	if !filepath.IsAbs(dir) {
+		wd, err := utils.GetWorkDirectory()
+		if err != nil {
+			return err
+		}
+		dir = filepath.Join(wd, dir)
+	}
+	p, ok := g.decls[dir]
+	if !ok {
+		var err error
+		if p, err = internal.LoadPackage(dir); err != nil {
+			return err
+		}
+		if g.decls == nil {
+			g.decls = make(map[string]*types.Package, 2)
+		}
+		g.decls[dir] = p
+	}
+	obj, ok := p.Scope().Lookup(d.Name).(*types.TypeName)
+	if !ok {
+		return fmt.Errorf("%s: 未找到结构体 %s", utils.ShortPath(dir), d.Name)
+	}
+	named, ok := obj.Type().(*types.Named)
+	if !ok {
+		return fmt.Errorf("%s: 未找到结构体 %s", utils.ShortPath(dir), d.Name)
+	}
+	return e.ParseTypes(named, nil, nil)
+}
+
+// writeFile replaces the file at path through a temporary file in the same
+// directory, so that an interrupted generation never leaves a partial file.
+func writeFile(path string, code []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	_, err = f.Write(code)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(f.Name(), 0644)
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// checkFiles prints the unified diff between the files on disk and the generated
+// files, including the removal of the stale files, and returns an error listing the
+// files that differ.
+func checkFiles(paths []string, files map[string][]byte, stale []string) error {
+	changed := make([]string, 0, len(paths))
+	for _, path := range paths {
+		name := utils.ShortPath(path)
+		oldName := name
+		old, err := os.ReadFile(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			oldName = "/dev/null"
+		} else if err != nil {
+			return err
+		}
+		if diff := utils.UnifiedDiff(oldName, name, old, files[path]); diff != "" {
+			fmt.Print(diff)
+			changed = append(changed, name)
+		}
+	}
+	for _, path := range stale {
+		old, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		name := utils.ShortPath(path)
+		fmt.Print(utils.UnifiedDiff(name, "/dev/null", old, nil))
+		changed = append(changed, name)
+	}
+	if len(changed) > 0 {
+		return fmt.Errorf("generated files are out of date, run struct-validate validate: %s", strings.Join(changed, ", "))
 	}
-	//fmt.Println("generation is completed.")
 	return nil
 }
 
@@ -75,208 +310,369 @@ func genFilePath(dir, entityName string) string {
 	return filepath.Join(dir, utils.UnderscoreName(entityName)+"_validate.go")
 }
 
-func (g *GenDefinition) GenValidation() {
-	for _, entity := range g.entities {
-		for _, field := range entity.Fields {
-			entity.AddPackages(field.Packages...)
-		}
+// genKey identifies a struct type during one generation.
+func genKey(pkgRelPath, entityName string) string {
+	return pkgRelPath + "." + entityName
+}
 
-		entity.CustomFuncs = make([]*internal.FuncType, 0, 10)
+// GenValidation renders the parsed entities and their nested structs into the
+// generated files. Entities are rendered concurrently; the first error in the
+// order of the entities is returned.
+func (g *GenDefinition) GenValidation() error {
+	wd, err := utils.GetWorkDirectory()
+	if err != nil {
+		return err
+	}
+	g.wd = wd
+	g.generated = make(map[string]struct{}, len(g.entities))
+	g.files = make(map[string][]byte, len(g.entities))
+	g.sources = make(map[string]*source, len(g.entities))
+	g.packages = make(map[string][]*internal.Entity, len(g.entities))
+	for _, entity := range g.entities {
+		g.generated[genKey(entity.PkgRelPath, entity.EntityName)] = struct{}{}
+	}
 
-		//paths, err := utils.ScanFiles("./" + entity.PkgRelPath)
-		//if err != nil {
-		//	panic(err)
-		//}
-		//res, err := internal.ParseFile(paths)
-		wd, err := utils.GetWorkDirectory()
-		if err != nil {
-			panic(err)
-		}
-		dir := filepath.Join(wd, entity.PkgRelPath)
-		paths, err := utils.ScanFiles(dir)
+	errs := make([]error, len(g.entities))
+	sem := make(chan struct{}, runtime.GOMAXPROCS(0))
+	var wg sync.WaitGroup
+	for i, entity := range g.entities {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			errs[i] = g.genEntity(entity)
+		}()
+	}
+	wg.Wait()
+	for _, err := range errs {
 		if err != nil {
-			panic(err)
+			return err
 		}
+	}
 
-		res, err := internal.ParseFile(paths)
+	if g.combine {
+		if err := g.completePackages(); err != nil {
+			return err
+		}
+	} else if len(g.prune) > 0 {
+		if err := g.completeFiles(); err != nil {
+			return err
+		}
+	}
+	for dir, entities := range g.packages {
+		file := filepath.Join(dir, CombinedFileName)
+		code, err := renderPackage(entities)
 		if err != nil {
-			panic(err)
+			return fmt.Errorf("%s: %w", file, err)
 		}
-		for _, ft := range res.FuncType {
-			if entity.EntityName == ft.Recv.Value {
-				entity.CustomFuncs = append(entity.CustomFuncs, ft)
+		g.files[file] = code
+	}
+	return nil
+}
+
+// completePackages adds the structs of each package written in combine mode that
+// were not reached in this generation, so that rewriting its CombinedFileName keeps
+// their validators: the structs with validation tags and the types listed in the
+// existing file, such as nested structs reached from other packages. They are parsed
+// with go/types like a Decl, and may reach the structs of further packages.
+func (g *GenDefinition) completePackages() error {
+	tag := g.parseTag
+	if tag == "" {
+		tag = internal.DefaultParseTag
+	}
+	done := make(map[string]bool, len(g.packages))
+	for {
+		dirs := make([]string, 0, len(g.packages))
+		for dir := range g.packages {
+			if !done[dir] {
+				dirs = append(dirs, dir)
 			}
 		}
-
-		path := res.GetPath(entity.EntityName)
-		if path != "" {
-			entity.PkgRelPath = path
+		if len(dirs) == 0 {
+			return nil
 		}
-		if pg := res.GetPackage(entity.EntityName); pg != "" {
-			entity.PackageName = pg
+		sort.Strings(dirs)
+		for _, dir := range dirs {
+			done[dir] = true
+			res, err := g.parseDir(dir)
+			if err != nil {
+				return err
+			}
+			rel, err := filepath.Rel(g.wd, dir)
+			if err != nil {
+				return err
+			}
+			names := res.GetTaggedEntities(tag)
+			for _, name := range generatedTypes(filepath.Join(dir, CombinedFileName)) {
+				if slice.Contains[string](res.GetEntities(), name) {
+					names = append(names, name)
+				}
+			}
+			for _, name := range names {
+				if err := g.completeEntity(dir, rel, name); err != nil {
+					return err
+				}
+			}
 		}
+	}
+}
 
-		dir = filepath.Join(wd, entity.PkgRelPath)
-		if !utils.FileIsExist(dir) {
-			os.MkdirAll(dir, 0666)
+// completeFiles regenerates the files in the prune directories that were not written
+// in this generation but whose struct still exists, instead of removing them: like
+// completePackages, it keeps the validators of nested structs reached from other
+// packages, which are not generated when only the package of the struct is scanned.
+func (g *GenDefinition) completeFiles() error {
+	generated, err := GeneratedFiles(g.prune...)
+	if err != nil {
+		return err
+	}
+	for _, path := range generated {
+		if _, ok := g.files[path]; ok {
+			continue
 		}
-		file := genFilePath(dir, entity.EntityName)
-		var hasError bool
-		f, err := os.Create(file)
+		dir := filepath.Dir(path)
+		res, err := g.parseDir(dir)
 		if err != nil {
-			panic(err)
+			return err
 		}
-
-		defer func() {
-			f.Close()
-			if hasError {
-				os.Remove(file)
-			}
-		}()
-
-		t, err := template.New(entity.EntityName + "_service").Parse(tpl)
+		rel, err := filepath.Rel(g.wd, dir)
 		if err != nil {
-			hasError = true
-			panic(err)
-		}
-		if err := t.Execute(f, entity); err != nil {
-			hasError = true
-			panic(err)
-		}
-		createdFiles[file] = struct{}{}
-		// 生成嵌套结构体验证
-		for _, field := range entity.Fields {
-			if field.Fields != nil && entity.EntityName != "" && entity.PackageName != "" {
-				subGen(field.Fields, field.EntityName, field.Package, field.PkgRelPath)
+			return err
+		}
+		for _, name := range generatedTypes(path) {
+			if !slice.Contains[string](res.GetEntities(), name) {
+				continue
+			}
+			if err := g.completeEntity(dir, rel, name); err != nil {
+				return err
 			}
 		}
 	}
-
+	return nil
 }
 
-func subGen(subNodes []*internal.Node, entityName, packageName, pkgRelPath string) {
-	sub := &internal.Entity{
-		EntityName:  entityName,
-		PackageName: packageName,
-		PkgRelPath:  pkgRelPath,
-		Fields:      subNodes,
-		CustomFuncs: make([]*internal.FuncType, 0, 10),
+// completeEntity parses the struct name in dir with go/types and generates its
+// validate code, unless it was already generated.
+func (g *GenDefinition) completeEntity(dir, rel, name string) error {
+	key := genKey(filepath.ToSlash(rel), name)
+	if _, ok := g.generated[key]; ok {
+		return nil
+	}
+	g.generated[key] = struct{}{}
+	e := internal.NewEntity()
+	if g.parseTag != "" {
+		e.SetTag(g.parseTag)
+	}
+	if err := g.parseDecl(e, Decl{Dir: dir, Name: name}); err != nil {
+		return fmt.Errorf("parse %s: %w", name, err)
 	}
+	return g.genEntity(e)
+}
 
-	//if !sub.IsUseful() {
-	//	return
-	//}
+// subGen renders the validate code of the struct type of the nested field n once
+// per generation. The struct is parsed on its own, so its code is the same whichever
+// entity it is reached from.
+func (g *GenDefinition) subGen(n *internal.Node) error {
+	key := genKey(n.PkgRelPath, n.EntityName)
+	g.mu.Lock()
+	_, ok := g.generated[key]
+	g.generated[key] = struct{}{}
+	g.mu.Unlock()
+	if ok {
+		return nil
+	}
 
-	for _, subNode := range subNodes {
-		sub.AddPackages(subNode.Packages...)
+	sub := internal.NewEntity()
+	if g.parseTag != "" {
+		sub.SetTag(g.parseTag)
+	}
+	if err := sub.ParseNested(n); err != nil {
+		return fmt.Errorf("parse %s: %w", n.EntityName, err)
 	}
+	return g.genEntity(sub)
+}
 
-	wd, err := utils.GetWorkDirectory()
-	if err != nil {
-		panic(err)
+// source is the parse result of a package directory, shared by its entities.
+type source struct {
+	once sync.Once
+	res  *internal.ParseResult
+	err  error
+}
+
+// parseDir parses the package in dir once per generation.
+func (g *GenDefinition) parseDir(dir string) (*internal.ParseResult, error) {
+	g.mu.Lock()
+	src, ok := g.sources[dir]
+	if !ok {
+		src = &source{}
+		g.sources[dir] = src
 	}
-	dir := filepath.Join(wd, pkgRelPath)
-	paths, err := utils.ScanFiles(dir)
+	g.mu.Unlock()
+	src.once.Do(func() {
+		paths, err := utils.ScanFiles(dir)
+		if err != nil {
+			src.err = fmt.Errorf("scan %s: %w", dir, err)
+			return
+		}
+		src.res, src.err = internal.ParseFile(paths)
+	})
+	return src.res, src.err
+}
+
+// genEntity renders the validate code of entity and of its nested structs into g.files.
+func (g *GenDefinition) genEntity(entity *internal.Entity) error {
+	res, err := g.parseDir(filepath.Join(g.wd, entity.PkgRelPath))
 	if err != nil {
-		panic(err)
+		return fmt.Errorf("%s: %w", entity.EntityName, err)
 	}
 
-	sub.CustomFuncs = make([]*internal.FuncType, 0, 10)
-	res, err := internal.ParseFile(paths)
-	for _, ft := range res.FuncType {
-		if entityName == ft.Recv.Value {
-			sub.CustomFuncs = append(sub.CustomFuncs, ft)
+	entity.SetSignature(g.signature)
+	entity.Local = g.local
+	if g.combine {
+		dir, err := prepareEntity(g.wd, entity, res)
+		if err != nil {
+			return err
 		}
+		g.mu.Lock()
+		g.packages[dir] = append(g.packages[dir], entity)
+		g.mu.Unlock()
+	} else {
+		file, code, err := GenCode(g.wd, entity, res)
+		if err != nil {
+			return err
+		}
+		g.mu.Lock()
+		g.files[file] = code
+		g.mu.Unlock()
 	}
 
-	path := res.GetPath(sub.EntityName)
-	if path != "" {
-		sub.PkgRelPath = path
-	}
-	if pg := res.GetPackage(sub.EntityName); pg != "" {
-		sub.PackageName = pg
+	// 生成嵌套结构体以及 dive 元素的验证
+	for _, field := range entity.Fields {
+		for _, n := range []*internal.Node{field, field.Elem} {
+			if n == nil || n.Generic || !n.HasValidator() {
+				continue
+			}
+			if err := g.subGen(n); err != nil {
+				return err
+			}
+		}
 	}
+	return nil
+}
 
-	//exit, _ := utils.PathExist(sub.PkgRelPath)
-	//if !exit {
-	//	os.MkdirAll(sub.PkgRelPath, os.ModePerm)
-	//}
-	//file := genFilePath(sub.PkgRelPath, sub.EntityName)
-
-	//dir := filepath.Join(wd, sub.PkgRelPath)
-	dir = filepath.Join(wd, sub.PkgRelPath)
-	if !utils.FileIsExist(dir) {
-		os.MkdirAll(dir, 0666)
+// GenCode returns the path and the content of the validate file of entity, where
+// wd is the work directory and res the parse result of the entity's package.
+// The custom validators and the @path, @package annotations in res are applied to entity.
+func GenCode(wd string, entity *internal.Entity, res *internal.ParseResult) (string, []byte, error) {
+	dir, err := prepareEntity(wd, entity, res)
+	if err != nil {
+		return "", nil, err
 	}
-	file := genFilePath(dir, sub.EntityName)
-	var hasError bool
-	f, err := os.Create(file)
+	file := genFilePath(dir, entity.EntityName)
+	code, err := render(entity)
 	if err != nil {
-		panic(err)
+		return "", nil, fmt.Errorf("%s: %s: %w", file, entity.EntityName, err)
 	}
+	return file, code, nil
+}
 
-	defer func() {
-		f.Close()
-		if hasError {
-			os.Remove(file)
-		}
-	}()
-
-	t, err := template.New(entityName + "_service").Parse(tpl)
+// prepareEntity applies the custom validators, the functions of the func rules and
+// the annotations in res to entity, and returns the directory its validate code is written to.
+func prepareEntity(wd string, entity *internal.Entity, res *internal.ParseResult) (string, error) {
+	fts, err := res.CustomFuncs(entity.EntityName)
 	if err != nil {
-		hasError = true
-		panic(err)
+		return "", err
+	}
+	entity.CustomFuncs = fts
+
+	if err := entity.ResolveFuncs(res); err != nil {
+		return "", err
 	}
-	if err := t.Execute(f, sub); err != nil {
-		hasError = true
-		panic(err)
+	if err := entity.SetHelpers(); err != nil {
+		return "", err
 	}
 
-	createdFiles[file] = struct{}{}
-	for _, subNode := range subNodes {
-		if subNode.Fields != nil && subNode.EntityName != "" && subNode.Package != "" {
+	path := res.GetPath(entity.EntityName)
+	if entity.Local && path != "" && filepath.Clean(path) != filepath.Clean(entity.PkgRelPath) {
+		return "", fmt.Errorf("%s: %s%s 在结构体所在的包之外生成代码，不能与 --local 同时使用", entity.EntityName, internal.DefaultParsePath, path)
+	}
+	if path != "" {
+		entity.PkgRelPath = path
+	}
+	if pg := res.GetPackage(entity.EntityName); pg != "" {
+		entity.PackageName = pg
+	}
 
-			subGen(subNode.Fields, subNode.EntityName, subNode.Package, subNode.PkgRelPath)
+	if pos, ok := res.Positions[entity.EntityName]; ok {
+		if rel, err := filepath.Rel(wd, pos.Filename); err == nil {
+			entity.Source = filepath.ToSlash(rel)
 		}
 	}
+	return filepath.Join(wd, entity.PkgRelPath), nil
 }
 
 type ScanFile struct {
-	Files []string
+	Files         []string
+	Dirs          []string // Dirs 多个包的目录，不为空时忽略 Files，所有包在一次生成中处理
+	Check         bool     // Check 只检查生成的文件是否最新，见 GenDefinition.SetCheck
+	DryRun        bool     // DryRun 只列出将要生成的文件
+	Stdout        bool     // Stdout 将生成的代码打印到标准输出
+	Types         []string // Types 只生成这些名称的结构体，为空时生成所有带有验证标签的结构体
+	Prune         bool     // Prune 删除这些包中本次没有生成的旧文件，见 GenDefinition.SetPrune
+	Combine       bool     // Combine 每个包只生成一个文件，见 GenDefinition.SetCombine
+	Method        string   // Method 验证方法名，见 GenDefinition.SetMethod
+	Receiver      string   // Receiver 接收者名称，见 GenDefinition.SetReceiver
+	ValueReceiver bool     // ValueReceiver 使用值接收者，见 GenDefinition.SetReceiver
+	Local         bool     // Local 只在结构体所在的包中生成代码，见 GenDefinition.SetLocal
 }
 
-func (s *ScanFile) Resolver() error {
-	if len(s.Files) < 1 {
-		return errors.New("文件为空")
-	}
-
-	dir := filepath.Dir(s.Files[0])
+// scanPackage is a package whose structs are generated by Resolver.
+type scanPackage struct {
+	path     string          // path 导入路径
+	dir      string          // dir 包所在的绝对路径
+	entities []string        // entities 带有验证标签的结构体
+	decls    map[string]bool // decls 通过 Decl 静态解析的结构体，如泛型结构体
+}
 
+func (s *ScanFile) Resolver() error {
 	wd, err := utils.GetWorkDirectory()
 	if err != nil {
 		return err
 	}
-	if len(wd) > len(dir) {
-		return fmt.Errorf("不支持的操作，work directory：%s，file directory: %s", wd, dir)
-	}
-
 	// mod
 	module, err := utils.GetModule()
 	if err != nil {
 		return err
 	}
-	res, err := internal.ParseFile(s.Files)
+
+	var pkgs []*scanPackage
+	dirs := s.Dirs
+	if len(dirs) > 0 {
+		pkgs, err = scanPackages(wd, module, dirs)
+	} else {
+		if len(s.Files) < 1 {
+			return errors.New("文件为空")
+		}
+		var p *scanPackage
+		p, err = newScanPackage(wd, module, filepath.Dir(s.Files[0]), s.Files)
+		pkgs = []*scanPackage{p}
+		dirs = []string{filepath.Dir(s.Files[0])}
+	}
 	if err != nil {
 		return err
 	}
-	// 不处理main 包的验证
-	if res.Pkg == "main" {
-		// todo
-		return nil
+	if s.Prune && len(s.Types) > 0 {
+		return errors.New("只生成部分结构体时不能删除旧文件")
 	}
-
-	if len(res.Entities) == 0 {
-		return nil
+	sig := internal.Signature{Method: s.Method, Receiver: s.Receiver, Value: s.ValueReceiver}
+	if err := sig.Check(); err != nil {
+		return err
+	}
+	if len(s.Types) > 0 {
+		if err := filterTypes(pkgs, s.Types); err != nil {
+			return err
+		}
 	}
 
 	// write temp file
@@ -285,56 +681,226 @@ func (s *ScanFile) Resolver() error {
 	buf.WriteString("\r\n")
 	buf.WriteString("import (")
 	buf.WriteString("\r\n")
+	buf.WriteString(`"fmt"`)
+	buf.WriteString("\r\n")
+	buf.WriteString(`"os"`)
+	buf.WriteString("\r\n")
 	buf.WriteString(`"SJT/struct-validate/pkg"`)
 	buf.WriteString("\r\n")
-	buf.WriteString(`"`)
-	buf.WriteString(strings.Replace(filepath.Clean(filepath.Join(module, dir[len(wd):])), "\\", "/", -1))
-	buf.WriteString(`"`)
-	buf.WriteString("\r\n)\r\n")
+	entities := make([]string, 0, 10)
+	for i, p := range pkgs {
+		if p == nil {
+			continue
+		}
+		// 不同目录下的包可能同名，使用别名导入
+		alias := fmt.Sprintf("p%d", i)
+		imported := false
+		for _, entity := range p.entities {
+			if p.decls[entity] {
+				entities = append(entities, fmt.Sprintf("pkg.Decl{Dir: %q, Name: %q}", p.dir, entity))
+				continue
+			}
+			if !imported {
+				fmt.Fprintf(&buf, "%s %q\r\n", alias, p.path)
+				imported = true
+			}
+			entities = append(entities, alias+"."+entity+"{}")
+		}
+	}
+	// 没有结构体需要生成时仍要删除旧文件
+	if len(entities) == 0 && !s.Prune {
+		return nil
+	}
+	buf.WriteString(")\r\n")
 	buf.WriteString("func main() {")
 	buf.WriteString("\r\n")
 	buf.WriteString("g := pkg.NewGenDefinition()\r\n")
-	buf.WriteString("g.Gen(")
-	for i, entity := range res.GetEntities() {
-		buf.WriteString(res.Pkg + "." + entity + "{}")
-		if i < len(res.GetEntities())-1 {
-			buf.WriteString(",")
+	if s.Check {
+		buf.WriteString("g.SetCheck(true)\r\n")
+	}
+	if s.DryRun {
+		buf.WriteString("g.SetDryRun(true)\r\n")
+	}
+	if s.Stdout {
+		buf.WriteString("g.SetStdout(true)\r\n")
+	}
+	if s.Combine {
+		buf.WriteString("g.SetCombine(true)\r\n")
+	}
+	if s.Local {
+		buf.WriteString("g.SetLocal(true)\r\n")
+	}
+	if s.Method != "" {
+		fmt.Fprintf(&buf, "g.SetMethod(%q)\r\n", s.Method)
+	}
+	if s.Receiver != "" || s.ValueReceiver {
+		fmt.Fprintf(&buf, "g.SetReceiver(%q, %t)\r\n", s.Receiver, s.ValueReceiver)
+	}
+	if s.Prune {
+		buf.WriteString("g.SetPrune(")
+		for i, dir := range dirs {
+			if i > 0 {
+				buf.WriteString(",")
+			}
+			dir, err := filepath.Abs(dir)
+			if err != nil {
+				return err
+			}
+			buf.WriteString(strconv.Quote(dir))
 		}
+		buf.WriteString(")\r\n")
 	}
-	buf.WriteString(")")
+	buf.WriteString("if err := g.Gen(")
+	buf.WriteString(strings.Join(entities, ","))
+	buf.WriteString("); err != nil {")
 	buf.WriteString("\r\n")
+	buf.WriteString("fmt.Fprintln(os.Stderr, err)\r\n")
+	buf.WriteString("os.Exit(1)\r\n")
+	buf.WriteString("}\r\n")
 	buf.WriteString("}")
 
-	tempFolder := utils.RandString(6)
-	tempDir := filepath.Join(os.TempDir(), tempFolder)
-	os.MkdirAll(tempDir, 0666)
-
-	tempFile := filepath.Join(tempDir, "main.go")
-	f, err := os.OpenFile(tempFile, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0666)
+	// 临时程序放在模块根目录下才能导入模块中的 internal 包，以 . 开头的目录会被 go 命令忽略
+	tempDir, err := os.MkdirTemp(wd, ".struct-validate-")
 	if err != nil {
 		return err
 	}
-	defer func() {
-		f.Close()
-		os.RemoveAll(tempDir)
-		buf.Reset()
-	}()
+	defer os.RemoveAll(tempDir)
+
 	formatCode, err := format.Source(buf.Bytes())
 	if err != nil {
 		return err
 	}
-	f.Write(formatCode)
+	tempFile := filepath.Join(tempDir, "main.go")
+	if err := os.WriteFile(tempFile, formatCode, 0666); err != nil {
+		return err
+	}
 
 	cmd := exec.Command("go", "run", tempFile)
 	var output, stderr bytes.Buffer
 	cmd.Stdout = &output
 	cmd.Stderr = &stderr
-	if err := cmd.Run(); err != nil {
-		log.Fatal(err)
+	err = cmd.Run()
+	fmt.Print(output.String())
+	if err != nil {
+		// go run 会在标准错误末尾追加 "exit status N"
+		msg := strings.TrimSpace(stderr.String())
+		if i := strings.LastIndex(msg, "\nexit status "); i >= 0 {
+			msg = msg[:i]
+		}
+		if msg == "" {
+			return fmt.Errorf("generate: %w", err)
+		}
+		// 子进程的错误信息以 file:line:col 开头或已包含结构体名称
+		return errors.New(msg)
 	}
-	cmd.Wait()
-	fmt.Println(stderr.String())
-	fmt.Println(output.String())
-	//log.Println(string(cmdOut))
 	return nil
 }
+
+// filterTypes keeps only the entities named in types and reports the names not found in any package.
+func filterTypes(pkgs []*scanPackage, types []string) error {
+	found := make(map[string]bool, len(types))
+	for _, t := range types {
+		found[t] = false
+	}
+	for _, p := range pkgs {
+		if p == nil {
+			continue
+		}
+		entities := make([]string, 0, len(types))
+		for _, entity := range p.entities {
+			if _, ok := found[entity]; ok {
+				found[entity] = true
+				entities = append(entities, entity)
+			}
+		}
+		p.entities = entities
+	}
+	for _, t := range types {
+		if !found[t] {
+			return fmt.Errorf("未找到带有验证标签的结构体: %s", t)
+		}
+	}
+	return nil
+}
+
+// TypeAfterLine returns the name of the first struct declared after line in file,
+// such as the struct following a //go:generate directive. When pkgName is not
+// empty the file must belong to that package.
+func TypeAfterLine(file string, line int, pkgName string) (string, error) {
+	res, err := internal.ParseFile([]string{file})
+	if err != nil {
+		return "", err
+	}
+	if pkgName != "" && res.Pkg != pkgName {
+		return "", fmt.Errorf("%s: 包名为 %s，而不是 %s", utils.ShortPath(file), res.Pkg, pkgName)
+	}
+	name, declLine := "", 0
+	for _, entity := range res.GetEntities() {
+		pos := res.Positions[entity]
+		if pos.Line > line && (name == "" || pos.Line < declLine) {
+			name, declLine = entity, pos.Line
+		}
+	}
+	if name == "" {
+		return "", fmt.Errorf("%s:%d: 之后没有结构体声明", utils.ShortPath(file), line)
+	}
+	return name, nil
+}
+
+// scanPackages parses the packages in dirs concurrently. The result keeps the
+// order of dirs and holds nil for directories without structs to generate.
+func scanPackages(wd, module string, dirs []string) ([]*scanPackage, error) {
+	pkgs := make([]*scanPackage, len(dirs))
+	errs := make([]error, len(dirs))
+	sem := make(chan struct{}, runtime.GOMAXPROCS(0))
+	var wg sync.WaitGroup
+	for i, dir := range dirs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			files, err := utils.ScanFiles(dir)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			pkgs[i], errs[i] = newScanPackage(wd, module, dir, files)
+		}()
+	}
+	wg.Wait()
+	return pkgs, errors.Join(errs...)
+}
+
+// newScanPackage parses the files of the package in dir, and returns nil when
+// the package has no structs to generate.
+func newScanPackage(wd, module, dir string, files []string) (*scanPackage, error) {
+	if len(files) == 0 {
+		return nil, nil
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(wd) > len(dir) {
+		return nil, fmt.Errorf("不支持的操作，work directory：%s，file directory: %s", wd, dir)
+	}
+	res, err := internal.ParseFile(files)
+	if err != nil {
+		return nil, err
+	}
+	// 没有验证标签的结构体不会生成代码，也不必导入其所在的包
+	entities := res.GetTaggedEntities(internal.DefaultParseTag)
+	if len(entities) == 0 {
+		return nil, nil
+	}
+	path := strings.Replace(filepath.Clean(filepath.Join(module, dir[len(wd):])), "\\", "/", -1)
+	// 临时程序不能导入 main 包，其中的结构体与泛型结构体一样静态解析
+	decls := make(map[string]bool, len(res.Generics))
+	for _, entity := range entities {
+		if res.Generics[entity] || res.Pkg == "main" {
+			decls[entity] = true
+		}
+	}
+	return &scanPackage{path: path, dir: dir, entities: entities, decls: decls}, nil
+}
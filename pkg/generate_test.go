@@ -0,0 +1,305 @@
+package pkg
+
+import (
+	"SJT/struct-validate/internal"
+	"SJT/struct-validate/test_data/b"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type Order struct {
+	Id   int     `check:"gt 0"`
+	Name string  `check:"notEmpty"`
+	Addr *Street `check:"required"`
+	Note string
+}
+
+type Street struct {
+	City string `check:"notEmpty"`
+}
+
+func renderEntity(t *testing.T, entity any) string {
+	e := internal.NewEntity()
+	require.NoError(t, e.Parser(entity))
+	code, err := render(e)
+	require.NoError(t, err)
+	_, err = parser.ParseFile(token.NewFileSet(), "", code, 0)
+	require.NoError(t, err, string(code))
+	return string(code)
+}
+
+func TestRenderValidateFields(t *testing.T) {
+	code := renderEntity(t, Order{})
+	assert.Contains(t, code, "func (t *Order) ValidateFields(paths ...string) error {")
+	assert.Contains(t, code, `case "name", "Name":`)
+	assert.Contains(t, code, `case "addr", "Addr":`)
+	assert.Contains(t, code, "} else if t.Addr != nil {")
+	assert.Contains(t, code, "t.Addr.ValidateFields(sub)")
+	assert.Contains(t, code, `return errors.New("未知字段: " + path)`)
+}
+
+func TestRenderSignature(t *testing.T) {
+	e := internal.NewEntity()
+	require.NoError(t, e.Parser(Order{}))
+	e.SetSignature(internal.Signature{Method: "Validate", Receiver: "o", Value: true})
+	code, err := render(e)
+	require.NoError(t, err)
+	_, err = parser.ParseFile(token.NewFileSet(), "", code, 0)
+	require.NoError(t, err, string(code))
+
+	assert.Contains(t, string(code), "func (o Order) Validate() error {\n\treturn o.ValidateContext(context.Background())\n}")
+	assert.Contains(t, string(code), "func (o Order) ValidateContext(ctx context.Context) error {\n\tif o.Id <= 0 {")
+	assert.Contains(t, string(code), "if err := o.Addr.ValidateContext(ctx); err != nil {")
+	assert.Contains(t, string(code), "if sub == \"\" {\n\t\t\t\tif err := o.Addr.Validate(); err != nil {")
+	assert.Contains(t, string(code), "func (o Order) ValidateFields(paths ...string) error {")
+	assert.Contains(t, string(code), "} else if o.Addr != nil {\n\t\t\t\tif err := o.Addr.ValidateFields(sub); err != nil {")
+	assert.NotContains(t, string(code), " t.")
+}
+
+type Profile struct {
+	Email  string         `check:"omitempty;email"`
+	Age    *int           `check:"omitempty;gt 0"`
+	Score  float64        `check:"omitempty;gte 1"`
+	Tags   []string       `check:"omitempty;required"`
+	Labels map[string]int `check:"omitempty"`
+}
+
+func TestRenderOmitEmpty(t *testing.T) {
+	code := renderEntity(t, Profile{})
+	assert.Contains(t, code, "if t.Email != \"\" {\n\t\tif !regexp.MustCompile(")
+	assert.Contains(t, code, "if t.Age != nil {\n\t\tif *t.Age <= 0 {")
+	assert.Contains(t, code, "if t.Score != 0 {\n\t\tif t.Score < 1 {")
+	assert.Contains(t, code, "if len(t.Tags) != 0 {")
+	assert.Contains(t, code, "if len(t.Labels) != 0 {")
+}
+
+func TestRenderHeader(t *testing.T) {
+	code := renderEntity(t, Account{})
+	assert.True(t, strings.HasPrefix(code, GeneratedHeader+"\n// versions:\n// \tstruct-validate (devel)\n// type: Account\n\npackage pkg\n"), code)
+	formatted, err := format.Source([]byte(code))
+	require.NoError(t, err)
+	assert.Equal(t, string(formatted), code)
+}
+
+func TestImportGroups(t *testing.T) {
+	groups := importGroups([]string{"time", "SJT/struct-validate/test_data/b", "errors", "github.com/google/uuid"})
+	assert.Equal(t, [][]string{
+		{"errors", "time"},
+		{"SJT/struct-validate/test_data/b", "github.com/google/uuid"},
+	}, groups)
+	assert.Equal(t, [][]string{{"errors"}}, importGroups([]string{"errors"}))
+}
+
+type Account struct {
+	Name    string    `check:"required"`
+	Age     int       `check:"nonzero"`
+	Active  bool      `check:"required"`
+	Id      *int      `check:"required"`
+	Created time.Time `check:"required"`
+	Street  Street    `check:"nonzero"`
+}
+
+func TestRenderRequired(t *testing.T) {
+	code := renderEntity(t, Account{})
+	assert.Contains(t, code, "if t.Name == \"\" {\n\t\treturn errors.New(\"name不能为空\")")
+	assert.Contains(t, code, "if t.Age == 0 {")
+	assert.Contains(t, code, "if !t.Active {")
+	assert.Contains(t, code, "if t.Id == nil {\n\t\treturn errors.New(\"Id 不能为nil \")")
+	assert.Contains(t, code, "if t.Created == (time.Time{}) {")
+	assert.Contains(t, code, "\"time\"")
+	assert.Contains(t, code, "if t.Street == (Street{}) {")
+	assert.Contains(t, code, "if err := t.Street.Validator(); err != nil {")
+}
+
+type Member struct {
+	Role    string  `check:"eq admin"`
+	Title   string  `check:"ne \"hello world\""`
+	Team    *string `check:"omitempty;eqfold Core"`
+	Status  string  `check:"nefold banned"`
+	Code    string  `check:"lexical;gte a;lt n"`
+	Active  bool    `check:"eq true"`
+	Deleted bool    `check:"ne true"`
+}
+
+func TestRenderComparison(t *testing.T) {
+	code := renderEntity(t, Member{})
+	assert.Contains(t, code, "if t.Role != \"admin\" {\n\t\treturn errors.New(\"role必须 eq admin\")")
+	assert.Contains(t, code, "if t.Title == \"hello world\" {\n\t\treturn errors.New(\"title必须 ne \\\"hello world\\\"\")")
+	assert.Contains(t, code, "if !strings.EqualFold(*t.Team, \"Core\") {")
+	assert.Contains(t, code, "if strings.EqualFold(t.Status, \"banned\") {")
+	assert.Contains(t, code, "if t.Code < \"a\" {")
+	assert.Contains(t, code, "if t.Code >= \"n\" {")
+	assert.Contains(t, code, "if !t.Active {")
+	assert.Contains(t, code, "if t.Deleted {")
+}
+
+type Sku struct {
+	Code  string `check:"regexp ^[A-Z]{3}$"`
+	Email string `check:"email"`
+}
+
+type Item struct {
+	Sku     string `check:"regexp ^[A-Z]{3}$"`
+	Barcode string `check:"regexp ^[0-9]+$"`
+}
+
+func TestRenderPackage(t *testing.T) {
+	entities := make([]*internal.Entity, 0, 2)
+	for _, entity := range []any{Sku{}, Item{}} {
+		e := internal.NewEntity()
+		require.NoError(t, e.Parser(entity))
+		entities = append(entities, e)
+	}
+	code, err := renderPackage(entities)
+	require.NoError(t, err)
+	_, err = parser.ParseFile(token.NewFileSet(), "", code, 0)
+	require.NoError(t, err, string(code))
+
+	assert.Contains(t, string(code), "// types:\n// \tItem\n// \tSku\n")
+	assert.Contains(t, string(code), ")\n\nvar (\n\tregexpCustom1 = regexp.MustCompile(`^[A-Z]{3}$`)\n\tregexpCustom2 = regexp.MustCompile(`^[0-9]+$`)\n\tregexpEmail   = regexp.MustCompile(")
+	assert.Equal(t, 3, strings.Count(string(code), "regexp.MustCompile("))
+	assert.Contains(t, string(code), "if !regexpCustom1.MatchString(t.Code) {")
+	assert.Contains(t, string(code), "if !regexpCustom1.MatchString(t.Sku) {")
+	assert.Less(t, strings.Index(string(code), "func (t *Item) Validator()"), strings.Index(string(code), "func (t *Sku) Validator()"))
+}
+
+type Shipment struct {
+	To      b.Address `check:"required"`
+	From    *b.Address
+	Created time.Time `check:"required"`
+}
+
+func TestRenderHelpers(t *testing.T) {
+	e := internal.NewEntity()
+	require.NoError(t, e.Parser(Shipment{}))
+	// 模拟模块之外的类型
+	for _, field := range e.Fields {
+		field.Package, field.PkgRelPath = "", ""
+	}
+	require.NoError(t, e.SetHelpers())
+	require.Len(t, e.Helpers, 2)
+	code, err := render(e)
+	require.NoError(t, err)
+	_, err = parser.ParseFile(token.NewFileSet(), "", code, 0)
+	require.NoError(t, err, string(code))
+
+	assert.Contains(t, string(code), "\t\"SJT/struct-validate/test_data/b\"\n")
+	assert.Contains(t, string(code), "if err := validateShipmentAddress(ctx, &t.To); err != nil {")
+	assert.Contains(t, string(code), "func validateShipmentAddress(ctx context.Context, v *b.Address) error {\n\tif v.AddressId <= 10 {")
+	assert.Contains(t, string(code), "if v.Detail == (b.Detail{}) {")
+	assert.Contains(t, string(code), "if err := validateShipmentDetail(ctx, &v.Detail); err != nil {")
+	assert.Contains(t, string(code), "func validateShipmentDetail(ctx context.Context, v *b.Detail) error {")
+	assert.Contains(t, string(code), "if sub == \"\" {\n\t\t\t\tctx := context.Background()\n\t\t\t\tif err := validateShipmentAddress(ctx, &t.To); err != nil {")
+	assert.NotContains(t, string(code), "ValidateContext(ctx)")
+	assert.NotContains(t, string(code), "validateShipmentTime")
+}
+
+type Invalid struct {
+	Level uint8 `check:"lt 300"`
+}
+
+func TestGenError(t *testing.T) {
+	err := NewGenDefinition().Gen(Invalid{})
+	assert.EqualError(t, err, "parse pkg.Invalid: Invalid.Level: lt 300: constant 300 overflows uint8")
+}
+
+func TestTypeAfterLine(t *testing.T) {
+	tests := []struct {
+		name    string
+		line    int
+		pkg     string
+		want    string
+		wantErr string
+	}{
+		{name: "first", line: 1, pkg: "b", want: "Category"},
+		{name: "second", line: 4, want: "Owner"},
+		{name: "last", line: 30, wantErr: "之后没有结构体声明"},
+		{name: "package", line: 1, pkg: "main", wantErr: "包名为 b，而不是 main"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := TypeAfterLine("../test_data/b/category.go", tt.line, tt.pkg)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+// writePruneFixture writes the packages a and b under testdata/prune, where b.Outer
+// nests a.Inner, a struct without rules of its own, and returns their directories.
+func writePruneFixture(t *testing.T) (string, string) {
+	root, err := filepath.Abs("testdata/prune")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		os.RemoveAll(root)
+		os.Remove(filepath.Dir(root))
+	})
+	dirA, dirB := filepath.Join(root, "a"), filepath.Join(root, "b")
+	require.NoError(t, os.MkdirAll(dirA, os.ModePerm))
+	require.NoError(t, os.MkdirAll(dirB, os.ModePerm))
+	require.NoError(t, os.WriteFile(filepath.Join(dirA, "a.go"), []byte(`package a
+
+type Inner struct {
+	Leaf
+}
+
+type Leaf struct {
+	Code string `+"`check:\"notEmpty\"`"+`
+}
+`), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dirB, "b.go"), []byte(`package b
+
+import "SJT/struct-validate/pkg/testdata/prune/a"
+
+type Outer struct {
+	Inner a.Inner `+"`check:\"required\"`"+`
+}
+`), 0644))
+	return dirA, dirB
+}
+
+func TestGenPruneNested(t *testing.T) {
+	dirA, dirB := writePruneFixture(t)
+	require.NoError(t, NewGenDefinition().Gen(Decl{Dir: dirB, Name: "Outer"}))
+	inner := filepath.Join(dirA, "inner_validate.go")
+	require.FileExists(t, inner)
+	old := filepath.Join(dirA, "renamed_validate.go")
+	require.NoError(t, os.WriteFile(old, []byte(GeneratedHeader+"\n// type: Renamed\n\npackage a\n"), 0644))
+
+	// 只扫描 a 时 Inner 没有验证标签，但 b 的验证代码调用它
+	g := NewGenDefinition()
+	g.SetPrune(dirA)
+	files, err := g.Generate(Decl{Dir: dirA, Name: "Leaf"})
+	require.NoError(t, err)
+	assert.Contains(t, files, inner)
+	stale, err := g.staleFiles(files)
+	require.NoError(t, err)
+	assert.Equal(t, []string{old}, stale)
+
+	g = NewGenDefinition()
+	g.SetPrune(dirA)
+	require.NoError(t, g.Gen(Decl{Dir: dirA, Name: "Leaf"}))
+	assert.FileExists(t, inner)
+	assert.NoFileExists(t, old)
+
+	// Inner 删除后其文件被删除
+	require.NoError(t, os.WriteFile(filepath.Join(dirA, "a.go"), []byte("package a\n\ntype Leaf struct {\n\tCode string `check:\"notEmpty\"`\n}\n"), 0644))
+	g = NewGenDefinition()
+	g.SetPrune(dirA)
+	require.NoError(t, g.Gen(Decl{Dir: dirA, Name: "Leaf"}))
+	assert.NoFileExists(t, inner)
+	assert.FileExists(t, filepath.Join(dirA, "leaf_validate.go"))
+}
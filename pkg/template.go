@@ -1,50 +1,517 @@
 package pkg
 
-const tpl = `package {{ .PackageName }}
+import (
+	"SJT/struct-validate/internal"
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime/debug"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"golang.org/x/tools/go/ast/astutil"
+)
+
+const tpl = GeneratedHeader + `
+// versions:
+// 	struct-validate {{ version }}
+{{- with .Source }}
+// source: {{ . }}
+{{- end }}
+// type: {{ .EntityName }}
+
+package {{ .PackageName }}
 
 import (
-	{{- range $ip, $package := .Packages }}
+{{- range $i, $group := importGroups .Packages }}
+{{- if $i }}
+{{ end }}
+	{{- range $package := $group }}
 	"{{$package}}"
 	{{- end}}
+{{- end }}
 )
+{{ template "methods" . }}
+`
 
-{{ $receiver :=.EntityName -}}
-func (t *{{ $receiver -}}) Validator() error {
-	{{- define "main" -}}
-	{{- range $if, $field := .Fields -}}
-	{{ $starType :=.GetStarType -}}
-	{{ $shouldNil :=.ShouldValidateNil -}}
-	{{- range $it, $tag := $field.Tags -}}
-	{{- if and  (eq $tag.Operator "required") (eq $shouldNil true) -}}
-	if t.{{ $field.Field }} == nil {
-		return errors.New("{{ $field.Field }} 不能为nil ")
-	}
-	{{- end -}}
-	{{- $exist :=.Check $tag.Operator -}}
-	{{- if and (ne $tag.Operator "") (eq $exist true) -}}
-	{{- $get := .GetExp $field.Field $starType $tag.Operator $tag.Value $field.RealType -}}
-	{{- if (ne $get "") -}}
+// packageTpl renders the validators of every struct in a package into one file.
+const packageTpl = GeneratedHeader + `
+// versions:
+// 	struct-validate {{ version }}
+{{- with .Sources }}
+// sources:
+{{- range . }}
+// 	{{ . }}
+{{- end }}
+{{- end }}
+// types:
+{{- range .Entities }}
+// 	{{ .EntityName }}
+{{- end }}
+
+package {{ .PackageName }}
+
+import (
+{{- range $i, $group := importGroups .Packages }}
+{{- if $i }}
+{{ end }}
+	{{- range $package := $group }}
+	"{{$package}}"
+	{{- end}}
+{{- end }}
+)
+{{ range .Entities }}
+{{- template "methods" . }}
+{{ end -}}
+`
+
+// methodsTpl holds the templates shared by tpl and packageTpl.
+const methodsTpl = `
+{{- define "field" -}}
+{{- $field := . -}}
+{{- range $it, $tag := $field.Tags -}}
+{{- if eq $tag.Operator "omitempty" }}
+	if {{ $field.NotZeroExp }} {
+{{- end -}}
+{{- if and (or (eq $tag.Operator "required") (eq $tag.Operator "nonzero")) (ne $field.ZeroExp "") }}
+	if {{ $field.ZeroExp }} {
+		return errors.New("{{ $field.RequiredError }}")
+	}
+{{- end -}}
+{{- $exist := .Check $tag.Operator -}}
+{{- if and (ne $tag.Operator "") (eq $exist true) -}}
+{{- $get := $field.Exp $tag -}}
+{{- if (ne $get "") }}
 	if {{$get}} {
-		return errors.New("{{.GeError $field.Field $tag.Operator $tag.Value}}")
+		return errors.New({{ printf "%q" (.GeError $field.Field $tag.Operator $tag.Value) }})
+	}
+{{- else if and ($field.IsFuncError $tag) ($field.FuncGuard $tag) }}
+	if {{ $field.FuncGuard $tag }} {
+		if err := {{ $field.FuncCall $tag }}; err != nil {
+			return err
+		}
+	}
+{{- else if $field.IsFuncError $tag }}
+	if err := {{ $field.FuncCall $tag }}; err != nil {
+		return err
+	}
+{{- end -}}
+{{- end -}}
+{{- end -}}
+{{- if $field.HasTag "omitempty" }}
+	}
+{{- end -}}
+{{- end -}}
+
+{{- define "nested" -}}
+{{- if and .ValidatesNested .HasValidator }}
+	if err := {{ .Ref }}.ValidateContext(ctx); err != nil {
+		return err
+	}
+{{- else if and .ValidatesNestedNotNil .HasValidator }}
+	if {{ .Ref }} != nil {
+		if err := {{ .Ref }}.ValidateContext(ctx); err != nil {
+			return err
+		}
 	}
-	{{- end -}}
-	{{- end -}}
-	{{- if (eq $tag.Operator "required") -}}
-	{{- if (eq $field.RealType "struct") }}
-	if err := t.{{- $field.Field -}}.Validator(); err != nil {
+{{- else if and .ValidatesNested .Helper }}
+	if err := {{ .Helper }}(ctx, {{ .HelperArg }}); err != nil {
 		return err
 	}
-	{{- end -}}
+{{- else if and .ValidatesNestedNotNil .Helper }}
+	if {{ .Ref }} != nil {
+		if err := {{ .Helper }}(ctx, {{ .Ref }}); err != nil {
+			return err
+		}
+	}
+{{- else if .ConstraintMethod }}
+	if v := reflect.ValueOf({{ .AnyRef }}); v.IsValid() && !(v.Kind() == reflect.Pointer && v.IsNil()) {
+		if err := {{ .Ref }}.{{ .ConstraintMethod }}({{ if eq .ConstraintMethod "ValidateContext" }}ctx{{ end }}); err != nil {
+			return err
+		}
+	}
+{{- else if .IsInterface }}
+	if v := reflect.ValueOf({{ .AnyRef }}); v.IsValid() && !(v.Kind() == reflect.Pointer && v.IsNil()) {
+		switch validator := {{ .AnyRef }}.(type) {
+		case interface{ ValidateContext(context.Context) error }:
+			if err := validator.ValidateContext(ctx); err != nil {
+				return err
+			}
+		case interface{ {{ .MethodName }}() error }:
+			if err := validator.{{ .MethodName }}(); err != nil {
+				return err
+			}
+		}
+	}
+{{- end -}}
+{{- end }}
+
+
+{{- define "dive" -}}
+{{- with .Elem }}
+	for i := range {{ $.Ref }} {
+		{{- template "nested" . }}
+	}
+{{- end -}}
+{{- end }}
+
+{{- define "methods" }}
+{{ $sig := .Signature -}}
+{{ $receiver := $sig.ReceiverName -}}
+func ({{ $receiver }} {{ $sig.ReceiverType .TypeName }}) {{ $sig.MethodName }}() error {
+	return {{ $receiver }}.ValidateContext(context.Background())
+}
+
+// ValidateContext validates the struct, passing ctx to the custom validators and the nested structs.
+func ({{ $receiver }} {{ $sig.ReceiverType .TypeName }}) ValidateContext(ctx context.Context) error {
+	{{- range $if, $field := .Fields -}}
+	{{ template "field" $field }}
+	{{- template "nested" $field }}
+	{{- template "dive" $field }}
 	{{- end }}
-	{{end -}}
-	{{ end -}}
-	{{- end}}
-	{{ template "main" . -}}
 	{{ range $ic, $cf := .CustomFuncs -}}
-	if err := t.{{$cf.Name}}(); err !=nil {
+	if err := {{ $receiver }}.{{$cf.Name}}({{ if $cf.Context }}ctx{{ end }}); err !=nil {
 		return err
 	}
 	{{end -}}
 	return nil
 }
+
+// ValidateFields validates only the fields named by paths, such as "name" or "addr.city".
+func ({{ $receiver }} {{ $sig.ReceiverType .TypeName }}) ValidateFields(paths ...string) error {
+	{{- if .Fields }}
+	for _, path := range paths {
+		name, sub, _ := strings.Cut(path, ".")
+		switch name {
+		{{- range $if, $field := .Fields }}
+		case "{{ $field.FieldPath }}", "{{ $field.Field }}":
+			{{- template "field" $field }}
+			{{- if $field.HasValidator }}
+			{{- if or $field.ValidatesNested $field.ValidatesNestedNotNil }}
+			if sub == "" {
+				{{- if $field.ValidatesNested }}
+				if err := {{ $field.Ref }}.{{ $field.MethodName }}(); err != nil {
+					return err
+				}
+				{{- else }}
+				if {{ $field.Ref }} != nil {
+					if err := {{ $field.Ref }}.{{ $field.MethodName }}(); err != nil {
+						return err
+					}
+				}
+				{{- end }}
+			} else {{ if eq $field.Kind "ptr" }}if {{ $field.Ref }} != nil {{ end }}{
+			{{- else }}
+			if sub != "" {{ if eq $field.Kind "ptr" }}&& {{ $field.Ref }} != nil {{ end }}{
+			{{- end }}
+				if err := {{ $field.Ref }}.ValidateFields(sub); err != nil {
+					return err
+				}
+			}
+			{{- else }}
+			{{- if $field.IsInterface }}
+			if sub == "" {
+				ctx := context.Background()
+				{{- template "nested" $field }}
+			} else if v := reflect.ValueOf({{ $field.AnyRef }}); v.IsValid() && !(v.Kind() == reflect.Pointer && v.IsNil()) {
+				switch validator := {{ $field.AnyRef }}.(type) {
+				case interface{ ValidateFields(...string) error }:
+					if err := validator.ValidateFields(sub); err != nil {
+						return err
+					}
+				default:
+					return errors.New("未知字段: " + path)
+				}
+			}
+			{{- else }}
+			{{- if or (and $field.Helper (or $field.ValidatesNested $field.ValidatesNestedNotNil)) $field.ConstraintMethod $field.Elem }}
+			if sub == "" {
+				{{- if $field.UsesContext }}
+				ctx := context.Background()
+				{{- end }}
+				{{- template "nested" $field }}
+				{{- template "dive" $field }}
+			}
+			{{- end }}
+			if sub != "" {
+				return errors.New("未知字段: " + path)
+			}
+			{{- end }}
+			{{- end }}
+		{{- end }}
+		{{- range .PromotedFields }}
+		{{- $embedded := .Embedded }}
+		case {{ range $i, $label := .Labels }}{{ if $i }}, {{ end }}"{{ $label }}"{{ end }}:
+			{{- if eq $embedded.Kind "ptr" }}
+			if {{ $embedded.Ref }} != nil {
+				if err := {{ $embedded.Ref }}.ValidateFields(path); err != nil {
+					return err
+				}
+			}
+			{{- else }}
+			if err := {{ $embedded.Ref }}.ValidateFields(path); err != nil {
+				return err
+			}
+			{{- end }}
+		{{- end }}
+		default:
+			return errors.New("未知字段: " + path)
+		}
+	}
+	{{- else }}
+	if len(paths) > 0 {
+		return errors.New("未知字段: " + paths[0])
+	}
+	{{- end }}
+	return nil
+}
+{{- range .Helpers }}
+
+// {{ .Name }} validates {{ .TypeName }}, which is declared outside the package.
+func {{ .Name }}(ctx context.Context, v *{{ .TypeName }}) error {
+	{{- range $if, $field := .Fields -}}
+	{{ template "field" $field }}
+	{{- template "nested" $field }}
+	{{- template "dive" $field }}
+	{{- end }}
+	return nil
+}
+{{- end }}
+{{ end -}}
 `
+
+var templateFuncs = template.FuncMap{
+	"version":      func() string { return Version },
+	"importGroups": importGroups,
+}
+
+var (
+	validateTemplate = template.Must(template.Must(template.New("validate").Funcs(templateFuncs).Parse(tpl)).Parse(methodsTpl))
+	packageTemplate  = template.Must(template.Must(template.New("package").Funcs(templateFuncs).Parse(packageTpl)).Parse(methodsTpl))
+)
+
+// Version is the version of struct-validate written in the header of generated files,
+// taken from the build information of the program running the generator.
+var Version = version()
+
+func version() string {
+	module := strings.TrimSuffix(reflect.TypeOf(GenDefinition{}).PkgPath(), "/pkg")
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "(devel)"
+	}
+	// 在本模块中运行时没有版本，版本控制信息会随提交变化，不写入生成的文件
+	for _, dep := range info.Deps {
+		if dep.Path == module && dep.Version != "" {
+			return dep.Version
+		}
+	}
+	return "(devel)"
+}
+
+// importGroups groups the imports like goimports: the standard library first,
+// then the other packages, each group sorted.
+func importGroups(packages []string) [][]string {
+	var std, others []string
+	for _, p := range packages {
+		if isStdPackage(p) {
+			std = append(std, p)
+		} else {
+			others = append(others, p)
+		}
+	}
+	sort.Strings(std)
+	sort.Strings(others)
+	groups := make([][]string, 0, 2)
+	for _, group := range [][]string{std, others} {
+		if len(group) > 0 {
+			groups = append(groups, group)
+		}
+	}
+	return groups
+}
+
+// isStdPackage reports whether the package at path is in the standard library.
+func isStdPackage(path string) bool {
+	elem, _, _ := strings.Cut(path, "/")
+	if strings.Contains(elem, ".") {
+		return false
+	}
+	fi, err := os.Stat(filepath.Join(build.Default.GOROOT, "src", path))
+	return err == nil && fi.IsDir()
+}
+
+// addPackages adds the imports needed by the fields of entity to entity.Packages.
+func addPackages(entity *internal.Entity) {
+	for _, field := range entity.Fields {
+		entity.AddPackages(field.Packages...)
+	}
+	for _, helper := range entity.Helpers {
+		entity.AddPackages(helper.Packages...)
+	}
+	entity.AddPackages("context", "errors")
+	for _, field := range entity.Fields {
+		// the nil check of interface and type parameter fields uses reflect, see the nested template.
+		if field.ChecksNil() {
+			entity.AddPackages("reflect")
+			break
+		}
+	}
+	if len(entity.Fields) > 0 {
+		entity.AddPackages("strings")
+	}
+}
+
+// render executes the validate template for entity and returns the generated source
+// formatted by gofmt.
+func render(entity *internal.Entity) ([]byte, error) {
+	addPackages(entity)
+	var buf bytes.Buffer
+	if err := validateTemplate.Execute(&buf, entity); err != nil {
+		return nil, err
+	}
+	code, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("format generated code: %w", err)
+	}
+	return code, nil
+}
+
+// packageFile is the data of packageTpl.
+type packageFile struct {
+	PackageName string
+	Packages    []string
+	Sources     []string
+	Entities    []*internal.Entity
+}
+
+// renderPackage renders the validators of entities, which belong to the same
+// package, into one file whose regular expressions are compiled once in
+// package level variables.
+func renderPackage(entities []*internal.Entity) ([]byte, error) {
+	sort.Slice(entities, func(i, j int) bool {
+		return entities[i].EntityName < entities[j].EntityName
+	})
+	f := &packageFile{PackageName: entities[0].PackageName, Entities: entities}
+	packages := make(map[string]struct{}, 5)
+	sources := make(map[string]struct{}, len(entities))
+	for _, entity := range entities {
+		if entity.PackageName != f.PackageName {
+			return nil, fmt.Errorf("%s: package %s differs from %s", entity.EntityName, entity.PackageName, f.PackageName)
+		}
+		addPackages(entity)
+		for _, p := range entity.Packages {
+			if _, ok := packages[p]; !ok {
+				packages[p] = struct{}{}
+				f.Packages = append(f.Packages, p)
+			}
+		}
+		if _, ok := sources[entity.Source]; !ok && entity.Source != "" {
+			sources[entity.Source] = struct{}{}
+			f.Sources = append(f.Sources, entity.Source)
+		}
+	}
+	sort.Strings(f.Sources)
+
+	var buf bytes.Buffer
+	if err := packageTemplate.Execute(&buf, f); err != nil {
+		return nil, err
+	}
+	code, err := hoistRegexps(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("format generated code: %w", err)
+	}
+	return code, nil
+}
+
+// hoistRegexps replaces the regexp.MustCompile calls with constant patterns in src
+// by package level variables, one per distinct pattern, declared after the imports.
+func hoistRegexps(src []byte) ([]byte, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[string]string, 5)
+	patterns := make([]string, 0, 5)
+	custom := 0
+	astutil.Apply(file, nil, func(c *astutil.Cursor) bool {
+		pattern, ok := mustCompilePattern(c.Node())
+		if !ok {
+			return true
+		}
+		name, ok := names[pattern]
+		if !ok {
+			if op, builtin := internal.RegexpOperator(pattern); builtin {
+				name = "regexp" + strings.ToUpper(op.String()[:1]) + op.String()[1:]
+			} else {
+				custom++
+				name = fmt.Sprintf("regexpCustom%d", custom)
+			}
+			names[pattern] = name
+			patterns = append(patterns, pattern)
+		}
+		c.Replace(&ast.Ident{NamePos: c.Node().Pos(), Name: name})
+		return true
+	})
+	if len(patterns) == 0 {
+		return format.Source(src)
+	}
+
+	var buf bytes.Buffer
+	if err := format.Node(&buf, fset, file); err != nil {
+		return nil, err
+	}
+	code := buf.Bytes()
+	// 在导入声明之后插入正则表达式变量
+	file, err = parser.ParseFile(token.NewFileSet(), "", code, parser.ImportsOnly)
+	if err != nil {
+		return nil, err
+	}
+	offset := int(file.End()) - 1
+	var decl bytes.Buffer
+	decl.WriteString("\n\nvar (\n")
+	for _, pattern := range patterns {
+		literal := strconv.Quote(pattern)
+		if !strings.Contains(pattern, "`") {
+			literal = "`" + pattern + "`"
+		}
+		fmt.Fprintf(&decl, "\t%s = regexp.MustCompile(%s)\n", names[pattern], literal)
+	}
+	decl.WriteString(")\n")
+	out := make([]byte, 0, len(code)+decl.Len())
+	out = append(out, code[:offset]...)
+	out = append(out, decl.Bytes()...)
+	out = append(out, code[offset:]...)
+	return format.Source(out)
+}
+
+// mustCompilePattern returns the pattern of a regexp.MustCompile call with a constant argument.
+func mustCompilePattern(n ast.Node) (string, bool) {
+	call, ok := n.(*ast.CallExpr)
+	if !ok || len(call.Args) != 1 {
+		return "", false
+	}
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok || sel.Sel.Name != "MustCompile" {
+		return "", false
+	}
+	if x, ok := sel.X.(*ast.Ident); !ok || x.Name != "regexp" {
+		return "", false
+	}
+	lit, ok := call.Args[0].(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return "", false
+	}
+	pattern, err := strconv.Unquote(lit.Value)
+	return pattern, err == nil
+}
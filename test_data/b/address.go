@@ -0,0 +1,29 @@
+package b
+
+import (
+	"context"
+	"errors"
+)
+
+type Address struct {
+	AddressId int `check:"gt 10"`
+	Province  string
+	City      string
+	Detail    Detail `check:"required"`
+}
+
+type Detail struct {
+	Detail string `check:"notEmpty"`
+}
+
+// ClosedCity is the context key of a city that addresses must not be in.
+type ClosedCity struct{}
+
+// checkCity rejects the address in the closed city of ctx.
+// @ext:check
+func (t Address) checkCity(ctx context.Context) error {
+	if city, _ := ctx.Value(ClosedCity{}).(string); city != "" && city == t.City {
+		return errors.New("city 暂停服务")
+	}
+	return nil
+}
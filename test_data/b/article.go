@@ -0,0 +1,26 @@
+package b
+
+// Base is embedded by Audited.
+type Base struct {
+	Sort int `check:"gt 0"`
+}
+
+// Audited embeds Base, whose fields are promoted to Article through it.
+type Audited struct {
+	Base
+	Editor string `check:"notEmpty"`
+}
+
+// Meta is embedded by pointer.
+type Meta struct {
+	Lang string `check:"notEmpty"`
+}
+
+// Article validates the embedded Audited, the embedded Meta when it is not nil,
+// and skips the embedded Address.
+type Article struct {
+	Audited
+	*Meta
+	Address `check:"-"`
+	Title   string `check:"notEmpty"`
+}
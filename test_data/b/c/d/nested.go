@@ -0,0 +1,24 @@
+package d
+
+import "SJT/struct-validate/test_data/b"
+
+type MyInt int
+
+type Nested struct {
+	Id        *int    `check:"gt 0;lte 100"`
+	MyInt     MyInt   `check:"lt 100;ne 10"`
+	Name      string  `check:"notEmpty"`
+	age       int     `check:"gte 0;lte 100"`
+	Score     float32 `check:"gt 0.00"`
+	Email     string  `check:"email"`
+	Max       string  `check:"max 10"`
+	Min       string  `check:"min 5"`
+	MyUUID    string  `check:"required;uuid"`
+	Slice     []int   `check:"required"`
+	Map       map[string]int
+	Chan      chan int `check:"required"`
+	b.Address `check:"required"`
+	Addr      *b.Address `check:"required"`
+	Phone     string     `check:"phone"`
+	Contact   string     `check:"omitempty;phone"`
+}
@@ -0,0 +1,15 @@
+package b
+
+// Category is a self-referential struct.
+type Category struct {
+	Name     string `check:"notEmpty"`
+	Parent   *Category
+	Children []*Category
+	Owner    *Owner
+}
+
+// Owner refers back to Category.
+type Owner struct {
+	Name     string    `check:"notEmpty"`
+	Category *Category `check:"required"`
+}
@@ -0,0 +1,13 @@
+package b
+
+// Chapter holds a Section by value, which refers back to Chapter.
+type Chapter struct {
+	Title   string `check:"notEmpty"`
+	Section Section
+}
+
+// Section refers back to the Chapter that holds it.
+type Section struct {
+	Sort    int `check:"gt 0"`
+	Chapter *Chapter
+}
@@ -0,0 +1,7 @@
+package b
+
+// Envelope carries a payload of any type, validated when it implements the validator.
+type Envelope struct {
+	Kind    string `check:"notEmpty"`
+	Payload any    `check:"required"`
+}
@@ -0,0 +1,25 @@
+package b
+
+import "context"
+
+// Validatable is implemented by the structs with generated validators.
+type Validatable interface {
+	ValidateContext(ctx context.Context) error
+}
+
+// Page validates the items that implement the validator at run time.
+type Page[T any] struct {
+	Items []T `check:"max 3;dive"`
+	Total int `check:"gte 0"`
+}
+
+// List validates its items and head through the constraint of T.
+type List[T Validatable] struct {
+	Items []T `check:"required;dive"`
+	Head  T
+}
+
+// Catalog holds an instance of a generic struct.
+type Catalog struct {
+	Addresses Page[*Address] `check:"required"`
+}
@@ -0,0 +1,16 @@
+package b
+
+// Route and Stop refer to each other, Trip reaches Stop without passing Route.
+type Route struct {
+	Name string `check:"notEmpty"`
+	Stop *Stop
+}
+
+// Stop has no rules of its own, its Route is validated as a recursive field.
+type Stop struct {
+	Route *Route
+}
+
+type Trip struct {
+	Stop Stop `check:"required"`
+}
@@ -0,0 +1,27 @@
+// Command app declares its request types in package main.
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// CreateUser is the request of the create user endpoint.
+type CreateUser struct {
+	Name    string   `check:"notEmpty"`
+	Email   string   `check:"email"`
+	Profile *Profile `check:"required"`
+}
+
+// Profile is nested in CreateUser.
+type Profile struct {
+	Age int `check:"gte 0;lte 150"`
+}
+
+func main() {
+	req := &CreateUser{Name: "a", Email: "a@b.cn", Profile: &Profile{Age: 200}}
+	if err := req.Validator(); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
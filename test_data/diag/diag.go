@@ -0,0 +1,22 @@
+package diag
+
+// Overflow has a tag argument that does not fit the field type.
+type Overflow struct {
+	Name string `check:"notEmpty"`
+	Age  uint8  `check:"gte 0;gt -1"` // want "constant -1 overflows uint8"
+}
+
+// Unordered orders strings without the lexical modifier.
+type Unordered struct {
+	Code string `check:"notEmpty;lt n"` // want "requires the lexical modifier"
+}
+
+// Misspelled has an unknown rule whose text also appears in an earlier rule.
+type Misspelled struct {
+	Email string `check:"email;mail"` // want "unknown operator"
+}
+
+// Prefixed has a longer tag key that ends with the rule key.
+type Prefixed struct {
+	Email string `xcheck:"email;mail" check:"email;mail"` // want "unknown operator"
+}
@@ -0,0 +1,18 @@
+// Package combined is generated with --combine.
+package combined
+
+// Fresh is up to date.
+type Fresh struct {
+	Name string `check:"notEmpty"`
+}
+
+// Stale has a rule that is not in the combined file.
+type Stale struct { // want "zz_generated_validate.go: validators of Stale are stale"
+	Code string `check:"notEmpty"`
+	Size int    `check:"gt 0"`
+}
+
+// Missing was added after the combined file was generated.
+type Missing struct { // want "zz_generated_validate.go: validators of Missing are missing"
+	Name string `check:"notEmpty"`
+}
@@ -0,0 +1,82 @@
+package lint
+
+import (
+	"errors"
+	"strings"
+)
+
+// Rules has tags the generator rejects.
+type Rules struct {
+	Role  string `check:"admin"`       // want `unknown operator "admin"`
+	Name  string `check:"notEmpty 1"`  // want "notEmpty takes 0 argument"
+	Age   int    `check:"gt"`          // want "gt takes 1 argument"
+	Tags  []int  `check:"email"`       // want "email is not supported on slice"
+	Code  string `check:"regexp [a-z"` // want "invalid regexp"
+	Title string `check:"max ten"`     // want "invalid int constant ten"
+}
+
+// Missing has valid tags but no generated file.
+type Missing struct { // want "missing_validate.go is missing"
+	Name string `check:"notEmpty"`
+}
+
+// Stale was generated before its tag changed.
+type Stale struct { // want "stale_validate.go is stale"
+	Name string `check:"notEmpty;max 20"`
+}
+
+// Unresolved names a function that is not declared.
+type Unresolved struct {
+	Sku string `check:"func validSku"` // want "func validSku: function not found"
+}
+
+// Mismatched names a function whose parameter does not accept the field.
+type Mismatched struct {
+	Count int `check:"gt 0;func validSKU"` // want "func validSKU: cannot use int as string"
+}
+
+// Custom has a custom validator with an invalid signature.
+type Custom struct {
+	Name string `check:"notEmpty"`
+}
+
+// @ext:check
+func (c *Custom) check(name string) error { // want `Custom.check: 自定义函数签名不正确`
+	return nil
+}
+
+// Fresh is up to date.
+type Fresh struct {
+	Code  string  `check:"regexp ^[a-z]+$"`
+	Sku   *string `check:"omitempty;func validSKU"`
+	Label string  `check:"func isUpper"`
+	// 没有 omitempty 的指针字段为 nil 时不调用函数
+	Backup *string `check:"func validSKU"`
+	Alias  *string `check:"func isUpper"`
+}
+
+func validSKU(sku string) error {
+	if len(sku) != 8 {
+		return errors.New("sku 必须是8位")
+	}
+	return nil
+}
+
+func isUpper(s string) bool {
+	return s == strings.ToUpper(s)
+}
+
+// Wrapper is up to date, but the file of its nested struct Inner is stale.
+type Wrapper struct { // want "nested struct Inner: inner_validate.go is stale"
+	Inner Inner `check:"required"`
+}
+
+// Inner has no rules of its own, its file is generated with Wrapper.
+type Inner struct {
+	Leaf
+}
+
+// Leaf is up to date.
+type Leaf struct {
+	Code string `check:"notEmpty"`
+}
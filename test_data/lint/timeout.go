@@ -0,0 +1,20 @@
+package lint
+
+import (
+	. "time"
+	tm "time"
+)
+
+// Timeout names functions whose parameters are written with a dot import and an import alias.
+type Timeout struct {
+	Wait  tm.Duration `check:"func positive"`
+	Delay *Duration   `check:"func short"`
+}
+
+func positive(d Duration) bool {
+	return d > 0
+}
+
+func short(d tm.Duration) bool {
+	return d < Hour
+}
@@ -0,0 +1,45 @@
+package utils
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// diffContext is the number of unchanged lines around each hunk.
+const diffContext = 3
+
+// noNewline marks a last line without a trailing newline, as diff does.
+const noNewline = "\n\\ No newline at end of file\n"
+
+// UnifiedDiff returns the unified diff from old to new, or "" when they are equal.
+func UnifiedDiff(oldName, newName string, old, new []byte) string {
+	if bytes.Equal(old, new) {
+		return ""
+	}
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        splitLines(old),
+		B:        splitLines(new),
+		FromFile: oldName,
+		ToFile:   newName,
+		Context:  diffContext,
+	})
+	if err != nil {
+		return err.Error()
+	}
+	return diff
+}
+
+// splitLines splits s into lines, each keeping its trailing newline. A last line
+// without one is followed by the no newline marker, so that it differs from the
+// same line ending with a newline.
+func splitLines(s []byte) []string {
+	lines := strings.SplitAfter(string(s), "\n")
+	last := len(lines) - 1
+	if lines[last] == "" {
+		return lines[:last]
+	}
+	lines[last] += noNewline
+	return lines
+}
@@ -0,0 +1,80 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ListPackages returns the sorted directories of the packages matched by Go package
+// patterns such as ".", "./..." or "./internal/...", leaving out the directories
+// matched by excludes. An exclude is a glob relative to the current directory and
+// also excludes the directories below the ones it matches.
+func ListPackages(patterns, excludes []string) ([]string, error) {
+	args := []string{"list", "-f", "{{.Dir}}"}
+	for _, p := range patterns {
+		// go list 将不以 . 开头的相对路径视为导入路径
+		if !strings.HasPrefix(p, ".") && !filepath.IsAbs(p) && FileIsExist(strings.SplitN(p, "/", 2)[0]) {
+			p = "./" + p
+		}
+		args = append(args, p)
+	}
+	cmd := exec.Command("go", args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, errors.New(msg)
+		}
+		return nil, err
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	dirs := make([]string, 0, 10)
+	for _, dir := range strings.Split(string(out), "\n") {
+		if dir == "" {
+			continue
+		}
+		skip, err := excluded(wd, dir, excludes)
+		if err != nil {
+			return nil, err
+		}
+		if !skip {
+			dirs = append(dirs, dir)
+		}
+	}
+	sort.Strings(dirs)
+	return dirs, nil
+}
+
+// excluded reports whether dir or one of its parent directories, relative to wd, matches a glob in excludes.
+func excluded(wd, dir string, excludes []string) (bool, error) {
+	rel, err := filepath.Rel(wd, dir)
+	if err != nil {
+		rel = dir
+	}
+	rel = filepath.ToSlash(rel)
+	for _, pattern := range excludes {
+		pattern = strings.TrimPrefix(filepath.ToSlash(pattern), "./")
+		pattern = strings.TrimSuffix(strings.TrimSuffix(pattern, "/..."), "/")
+		for p := rel; p != "." && p != "/"; p = path.Dir(p) {
+			ok, err := path.Match(pattern, p)
+			if err != nil {
+				return false, err
+			}
+			if ok {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
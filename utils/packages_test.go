@@ -0,0 +1,37 @@
+package utils
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListPackages(t *testing.T) {
+	root, err := filepath.Abs("..")
+	assert.NoError(t, err)
+	tests := []struct {
+		name     string
+		patterns []string
+		excludes []string
+		want     []string
+	}{
+		{name: "dir", patterns: []string{"../test_data/b"}, want: []string{"test_data/b"}},
+		{name: "recursive", patterns: []string{"../test_data/..."}, want: []string{"test_data/b", "test_data/b/c/d", "test_data/cmd/app", "test_data/diag", "test_data/lint", "test_data/lint/combined"}},
+		{name: "exclude", patterns: []string{"../test_data/..."}, excludes: []string{"../test_data/lint", "../test_data/d*"}, want: []string{"test_data/b", "test_data/b/c/d", "test_data/cmd/app"}},
+		{name: "exclude subtree", patterns: []string{"../test_data/..."}, excludes: []string{"../test_data/b/..."}, want: []string{"test_data/cmd/app", "test_data/diag", "test_data/lint", "test_data/lint/combined"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dirs, err := ListPackages(tt.patterns, tt.excludes)
+			assert.NoError(t, err)
+			got := make([]string, 0, len(dirs))
+			for _, dir := range dirs {
+				rel, err := filepath.Rel(root, dir)
+				assert.NoError(t, err)
+				got = append(got, filepath.ToSlash(rel))
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,21 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ShortPath returns path relative to the current directory when it is shorter,
+// the way the go command prints file names.
+func ShortPath(path string) string {
+	wd, err := os.Getwd()
+	if err != nil {
+		return path
+	}
+	rel, err := filepath.Rel(wd, path)
+	if err != nil || strings.HasPrefix(rel, "..") || len(rel) >= len(path) {
+		return path
+	}
+	return rel
+}